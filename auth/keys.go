@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/henrymxu/gosports/database"
+	"sync"
+	"time"
+)
+
+const KeysCollectionName = "apiKeys"
+
+const keyCacheDuration = 1 * time.Minute // How long a key is used before it is read from the database again
+
+type Scope string
+
+const (
+	ScheduleRead Scope = "schedule:read" // REST endpoints
+	LiveStream   Scope = "live:stream"   // Websocket and long poll endpoints
+	Admin        Scope = "admin"         // Key management, implies every other scope
+)
+
+// APIKey is stored by Id, the SHA-256 of the key, so that the key itself is only known to its owner
+type APIKey struct {
+	Id                string  `bson:"_id" json:"id"`
+	Key               string  `bson:"-" json:"key,omitempty"` // Only set when the key is created
+	Name              string  `bson:"name" json:"name"`
+	Scopes            []Scope `bson:"scopes" json:"scopes"`
+	RequestsPerMinute int     `bson:"requestsPerMinute" json:"requestsPerMinute"` // 0 for unlimited
+	MaxSockets        int     `bson:"maxSockets" json:"maxSockets"`               // Concurrent websockets, 0 for unlimited
+}
+
+// HasScope checks if the key was granted scope
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope || granted == Admin {
+			return true
+		}
+	}
+	return false
+}
+
+type cachedKey struct {
+	key     *APIKey
+	expires time.Time
+}
+
+// Keys stores api keys in the database, with a short lived in memory cache
+type Keys struct {
+	collection    database.Collection
+	bootstrapKeys map[string]*APIKey // Keys that are not stored in the database
+	cache         map[string]cachedKey
+	mutex         sync.Mutex
+}
+
+// CreateKeys creates a key store backed by collection.
+// bootstrapAdminKey, if not empty, is accepted as an admin key so that the first keys can be created.
+func CreateKeys(collection database.Collection, bootstrapAdminKey string) *Keys {
+	keys := &Keys{
+		collection:    collection,
+		bootstrapKeys: make(map[string]*APIKey),
+		cache:         make(map[string]cachedKey),
+	}
+	if bootstrapAdminKey != "" {
+		id := HashKey(bootstrapAdminKey)
+		keys.bootstrapKeys[id] = &APIKey{
+			Id:     id,
+			Name:   "bootstrap",
+			Scopes: []Scope{Admin},
+		}
+	}
+	return keys
+}
+
+// GetById returns the key with id, or database.ErrNotFound if it does not exist
+func (k *Keys) GetById(id string) (*APIKey, error) {
+	if apiKey, ok := k.bootstrapKeys[id]; ok {
+		return apiKey, nil
+	}
+	k.mutex.Lock()
+	cached, ok := k.cache[id]
+	k.mutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		if cached.key == nil {
+			return nil, database.ErrNotFound
+		}
+		return cached.key, nil
+	}
+	apiKey := &APIKey{}
+	err := k.collection.FindDocument(context.Background(), id, apiKey)
+	if err != nil && err != database.ErrNotFound {
+		return nil, err
+	}
+	if err == database.ErrNotFound {
+		apiKey = nil
+	}
+	k.mutex.Lock()
+	k.cache[id] = cachedKey{key: apiKey, expires: time.Now().Add(keyCacheDuration)}
+	k.mutex.Unlock()
+	return apiKey, err
+}
+
+// Create generates a new key for apiKey and stores it
+func (k *Keys) Create(apiKey *APIKey) error {
+	apiKey.Key = generateKey()
+	apiKey.Id = HashKey(apiKey.Key)
+	return k.collection.ReplaceDocument(context.Background(), apiKey.Id, apiKey)
+}
+
+// Delete removes the key with id
+func (k *Keys) Delete(id string) error {
+	err := k.collection.DeleteDocument(context.Background(), id)
+	if err == nil {
+		k.mutex.Lock()
+		delete(k.cache, id)
+		k.mutex.Unlock()
+	}
+	return err
+}
+
+// HashKey returns the id of key
+func HashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func generateKey() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"golang.org/x/time/rate"
+	"sync"
+	"time"
+)
+
+// Quotas enforces the request rate and concurrent websocket limits of each key
+type Quotas struct {
+	limiters map[string]*rate.Limiter
+	sockets  map[string]int
+	mutex    sync.Mutex
+}
+
+func CreateQuotas() *Quotas {
+	return &Quotas{
+		limiters: make(map[string]*rate.Limiter),
+		sockets:  make(map[string]int),
+	}
+}
+
+// AllowRequest consumes a request for the key, returning false and how long to wait if the rate is exceeded
+func (q *Quotas) AllowRequest(key *APIKey) (bool, time.Duration) {
+	if key.RequestsPerMinute <= 0 {
+		return true, 0
+	}
+	q.mutex.Lock()
+	limiter, ok := q.limiters[key.Id]
+	limit := rate.Every(time.Minute / time.Duration(key.RequestsPerMinute))
+	if !ok {
+		limiter = rate.NewLimiter(limit, key.RequestsPerMinute)
+		q.limiters[key.Id] = limiter
+	} else if limiter.Limit() != limit { // Key was updated
+		limiter.SetLimit(limit)
+		limiter.SetBurst(key.RequestsPerMinute)
+	}
+	q.mutex.Unlock()
+	reservation := limiter.Reserve()
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return false, delay
+	}
+	return true, 0
+}
+
+// AcquireSocket reserves a concurrent websocket for the key, returning false if the limit is reached
+func (q *Quotas) AcquireSocket(key *APIKey) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if key.MaxSockets > 0 && q.sockets[key.Id] >= key.MaxSockets {
+		return false
+	}
+	q.sockets[key.Id]++
+	return true
+}
+
+// ReleaseSocket releases a websocket reserved with AcquireSocket
+func (q *Quotas) ReleaseSocket(key *APIKey) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.sockets[key.Id] <= 1 {
+		delete(q.sockets, key.Id)
+		return
+	}
+	q.sockets[key.Id]--
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+var ErrExpiredToken = errors.New("expired token")
+
+// TokenClaims are the contents of a token, a token grants the api key's access limited to Sports and Games
+type TokenClaims struct {
+	KeyId    string   `json:"sub"`
+	Sports   []string `json:"sports,omitempty"` // Empty for every sport
+	Games    []string `json:"games,omitempty"`  // Empty for every game
+	IssuedAt int64    `json:"iat"`
+	Expires  int64    `json:"exp"`
+}
+
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyId     string `json:"kid"`
+}
+
+// Allows checks if the claims grant access to gameId of sport
+func (c *TokenClaims) Allows(sport string, gameId string) bool {
+	return contains(c.Sports, sport) && contains(c.Games, gameId)
+}
+
+func contains(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Signer signs and verifies HS256 JWTs.
+// Tokens are signed with the current key, and verified with any key so that keys can be rotated.
+type Signer struct {
+	secrets    map[string][]byte // Key id to secret
+	currentKid string
+}
+
+// CreateSigner creates a signer that signs with the secret of currentKid
+func CreateSigner(currentKid string, secrets map[string][]byte) *Signer {
+	signer := &Signer{
+		secrets:    make(map[string][]byte),
+		currentKid: currentKid,
+	}
+	for kid, secret := range secrets {
+		signer.secrets[kid] = secret
+	}
+	return signer
+}
+
+// Sign creates a token for claims
+func (s *Signer) Sign(claims TokenClaims) (string, error) {
+	kid := s.currentKid
+	secret := s.secrets[kid]
+	header, err := json.Marshal(tokenHeader{Algorithm: "HS256", Type: "JWT", KeyId: kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := encodeSegment(header) + "." + encodeSegment(payload)
+	return unsigned + "." + encodeSegment(sign(secret, unsigned)), nil
+}
+
+// Verify checks the signature and expiry of token, and returns its claims
+func (s *Signer) Verify(token string) (*TokenClaims, error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, ErrInvalidToken
+	}
+	header := tokenHeader{}
+	if err := decodeSegment(segments[0], &header); err != nil || header.Algorithm != "HS256" {
+		return nil, ErrInvalidToken
+	}
+	secret, ok := s.secrets[header.KeyId]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil || !hmac.Equal(signature, sign(secret, segments[0]+"."+segments[1])) {
+		return nil, ErrInvalidToken
+	}
+	claims := TokenClaims{}
+	if err := decodeSegment(segments[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.Expires {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+func sign(secret []byte, unsigned string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
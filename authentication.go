@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/henrymxu/gosports/auth"
+	"github.com/henrymxu/gosports/database"
+	"github.com/ngaut/log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const apiKeyHeader = "X-API-Key"
+const apiKeyQuery = "apiKey" // Websocket clients (browsers) cannot set headers
+const tokenQuery = "token"   // Short lived alternative to apiKeyQuery for websocket clients
+
+const defaultTokenDuration = 5 * time.Minute
+const maxTokenDuration = 1 * time.Hour
+
+type contextKey string
+
+const apiKeyContextKey = contextKey("apiKey")
+
+// authenticate requires a valid api key with scope, and enforces the key's request rate.
+// The key is stored in the request context, see requestAPIKey.
+func (s *server) authenticate(h http.HandlerFunc, scope auth.Scope) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey := s.authorize(w, s.requestKey(r), scope)
+		if apiKey == nil {
+			return
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey, apiKey)))
+	}
+}
+
+// authenticateToken requires either a token allowing the request's sport and game, or a valid api key, with scope.
+// Like authenticate, the (token's) key is stored in the request context.
+func (s *server) authenticateToken(w http.ResponseWriter, r *http.Request, scope auth.Scope) *http.Request {
+	token := r.URL.Query().Get(tokenQuery)
+	if token == "" {
+		if apiKey := s.authorize(w, s.requestKey(r), scope); apiKey != nil {
+			return r.WithContext(context.WithValue(r.Context(), apiKeyContextKey, apiKey))
+		}
+		return nil
+	}
+	claims, err := s.signer.Verify(token)
+	if err != nil {
+		logHttpError(w, &httpError{
+			http.StatusUnauthorized,
+			fmt.Sprintf("Token rejected: %s", err),
+		})
+		return nil
+	}
+	for _, sport := range s.requestSports(r) {
+		if !claims.Allows(sport, r.URL.Query().Get("gameId")) {
+			logHttpError(w, &httpError{
+				http.StatusForbidden,
+				"Token does not grant access to this game",
+			})
+			return nil
+		}
+	}
+	if apiKey := s.authorize(w, claims.KeyId, scope); apiKey != nil { // The key may have been revoked since the token was minted
+		return r.WithContext(context.WithValue(r.Context(), apiKeyContextKey, apiKey))
+	}
+	return nil
+}
+
+// requestSports returns the names of the sports a request accesses, from its {sport} parameter or its sports query
+// (every sport if it is empty)
+func (s *server) requestSports(r *http.Request) []string {
+	if sport, ok := mux.Vars(r)["sport"]; ok {
+		return []string{sport}
+	}
+	if names := splitQuery(r.URL.Query().Get("sports")); names != nil {
+		return names
+	}
+	names := make([]string, len(*s.sports))
+	for i, sport := range *s.sports {
+		names[i] = sport.Name()
+	}
+	return names
+}
+
+// requestKey returns the id of the api key sent with the request, or an empty string
+func (s *server) requestKey(r *http.Request) string {
+	key := r.Header.Get(apiKeyHeader)
+	if key == "" {
+		key = r.URL.Query().Get(apiKeyQuery)
+	}
+	if key == "" {
+		return ""
+	}
+	return auth.HashKey(key)
+}
+
+// authorize checks that the key with id exists, has scope and has not exceeded its request rate.
+// Errors are written to w and nil is returned if the key is not authorized.
+func (s *server) authorize(w http.ResponseWriter, id string, scope auth.Scope) *auth.APIKey {
+	if id == "" {
+		logHttpError(w, &httpError{
+			http.StatusUnauthorized,
+			"Missing api key",
+		})
+		return nil
+	}
+	apiKey, err := s.keys.GetById(id)
+	if err == database.ErrNotFound {
+		logHttpError(w, &httpError{
+			http.StatusUnauthorized,
+			"Invalid api key",
+		})
+		return nil
+	} else if err != nil {
+		log.Errorf("Error retrieving api key: %s", err)
+		logHttpError(w, &httpError{
+			http.StatusInternalServerError,
+			"Could not verify api key",
+		})
+		return nil
+	}
+	if !apiKey.HasScope(scope) {
+		logHttpError(w, &httpError{
+			http.StatusForbidden,
+			fmt.Sprintf("Api key is missing the %s scope", scope),
+		})
+		return nil
+	}
+	if ok, delay := s.quotas.AllowRequest(apiKey); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+		logHttpError(w, &httpError{
+			http.StatusTooManyRequests,
+			"Request rate limit reached",
+		})
+		return nil
+	}
+	return apiKey
+}
+
+// requestAPIKey returns the key of a request that passed authenticate, or nil
+func requestAPIKey(r *http.Request) *auth.APIKey {
+	apiKey, _ := r.Context().Value(apiKeyContextKey).(*auth.APIKey)
+	return apiKey
+}
+
+// handleCreateToken exchanges the request's api key for a token limited to the sports and gameId queries
+func (s *server) handleCreateToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		duration := defaultTokenDuration
+		if seconds, err := strconv.Atoi(query.Get("ttl")); err == nil && seconds > 0 {
+			duration = time.Duration(seconds) * time.Second
+			if duration > maxTokenDuration {
+				duration = maxTokenDuration
+			}
+		}
+		now := time.Now()
+		claims := auth.TokenClaims{
+			KeyId:    requestAPIKey(r).Id,
+			Sports:   splitQuery(query.Get("sports")),
+			Games:    splitQuery(query.Get("gameId")),
+			IssuedAt: now.Unix(),
+			Expires:  now.Add(duration).Unix(),
+		}
+		token, err := s.signer.Sign(claims)
+		if err != nil {
+			log.Errorf("Error signing token: %s", err)
+			logHttpError(w, &httpError{
+				http.StatusInternalServerError,
+				"Could not create token",
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":   token,
+			"expires": claims.Expires,
+		})
+	}
+}
+
+// splitQuery splits a comma separated query, returning nil if it is empty
+func splitQuery(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func (s *server) handleCreateKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey := auth.APIKey{}
+		if err := json.NewDecoder(r.Body).Decode(&apiKey); err != nil {
+			logHttpError(w, &httpError{
+				http.StatusBadRequest,
+				"Invalid api key body",
+			})
+			return
+		}
+		if err := s.keys.Create(&apiKey); err != nil {
+			log.Errorf("Error storing api key: %s", err)
+			logHttpError(w, &httpError{
+				http.StatusInternalServerError,
+				"Could not store api key",
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(apiKey)
+	}
+}
+
+func (s *server) handleGetKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apiKey, err := s.keys.GetById(mux.Vars(r)["id"])
+		if err == database.ErrNotFound {
+			logHttpError(w, &httpError{
+				http.StatusNotFound,
+				"Api key not found",
+			})
+			return
+		} else if err != nil {
+			log.Errorf("Error retrieving api key: %s", err)
+			logHttpError(w, &httpError{
+				http.StatusInternalServerError,
+				"Could not retrieve api key",
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(apiKey)
+	}
+}
+
+func (s *server) handleDeleteKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.keys.Delete(mux.Vars(r)["id"]); err != nil {
+			log.Errorf("Error deleting api key: %s", err)
+			logHttpError(w, &httpError{
+				http.StatusInternalServerError,
+				"Could not delete api key",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
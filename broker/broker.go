@@ -0,0 +1,70 @@
+package broker
+
+import (
+	"fmt"
+	"github.com/henrymxu/gosports/sports"
+	"github.com/henrymxu/gosports/websocket"
+	"github.com/ngaut/log"
+)
+
+const topicFormat = "gosports.%s.%s.%s" // gosports.{sport}.{gameId}.{kind}, kind is a watch message kind such as plays
+const queueSize = 1000
+
+// Publisher publishes messages to the topics of a message broker
+type Publisher interface {
+	Publish(topic string, data []byte) error
+	Close() error
+}
+
+type publication struct {
+	topic string
+	data  []byte
+}
+
+// Bridge publishes every message the watcher sends for a game to publisher, encoded like websocket frames.
+// Messages are published in order by a single goroutine, and dropped if the broker falls behind.
+type Bridge struct {
+	publisher Publisher
+	encoding  websocket.Encoding
+	mode      string // websocket.FullMode or websocket.PatchMode
+	queue     chan publication
+}
+
+// CreateBridge creates a bridge to publisher that encodes messages with encoding, as sent to websocket clients with mode
+func CreateBridge(publisher Publisher, encoding websocket.Encoding, mode string) *Bridge {
+	bridge := &Bridge{
+		publisher: publisher,
+		encoding:  encoding,
+		mode:      mode,
+		queue:     make(chan publication, queueSize),
+	}
+	go bridge.publish()
+	return bridge
+}
+
+// Topic returns the topic of the messages of kind about a game of sport
+func Topic(sport string, gameId string, kind string) string {
+	return fmt.Sprintf(topicFormat, sport, gameId, kind)
+}
+
+func (b *Bridge) GameMessage(sport sports.Sport, game sports.ScheduledGame, kind string, message websocket.Message) {
+	data, err := b.encoding.Encode(message.ForMode(b.mode))
+	if err != nil {
+		log.Errorf("Error encoding %s message for broker: %s", b.encoding.Name(), err)
+		return
+	}
+	select {
+	case b.queue <- publication{topic: Topic(sport.Name(), game.Id, kind), data: data}:
+	default:
+		log.Errorf("Broker queue is full, dropping %s message of %s game %s", kind, sport.Name(), game.Id)
+	}
+}
+
+// Goroutine function
+func (b *Bridge) publish() {
+	for publication := range b.queue {
+		if err := b.publisher.Publish(publication.topic, publication.data); err != nil {
+			log.Errorf("Error publishing to %s: %s", publication.topic, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package broker
+
+import (
+	"strings"
+	"sync"
+)
+
+const subscriptionBuffer = 100
+
+// Publication is a message received by a MemoryPublisher subscription
+type Publication struct {
+	Topic string
+	Data  []byte
+}
+
+type memorySubscription struct {
+	pattern []string
+	channel chan Publication
+}
+
+// MemoryPublisher is an in-process stand-in for a broker, for consumers in the same process and for testing.
+// Subscriptions use NATS subject wildcards, `*` matches one token and a trailing `>` matches the rest.
+type MemoryPublisher struct {
+	subscriptions map[*memorySubscription]bool
+	mutex         sync.RWMutex
+}
+
+func CreateMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{
+		subscriptions: make(map[*memorySubscription]bool),
+	}
+}
+
+// Subscribe returns a channel of the publications to topics matching pattern, and a function that ends the subscription.
+// Publications are dropped if the channel is full.
+func (m *MemoryPublisher) Subscribe(pattern string) (<-chan Publication, func()) {
+	subscription := &memorySubscription{
+		pattern: strings.Split(pattern, "."),
+		channel: make(chan Publication, subscriptionBuffer),
+	}
+	m.mutex.Lock()
+	m.subscriptions[subscription] = true
+	m.mutex.Unlock()
+	return subscription.channel, func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		if m.subscriptions[subscription] { // Not ended yet, by an earlier call or by Close
+			delete(m.subscriptions, subscription)
+			close(subscription.channel)
+		}
+	}
+}
+
+func (m *MemoryPublisher) Publish(topic string, data []byte) error {
+	tokens := strings.Split(topic, ".")
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for subscription := range m.subscriptions {
+		if !matches(subscription.pattern, tokens) {
+			continue
+		}
+		select {
+		case subscription.channel <- Publication{Topic: topic, Data: data}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close ends every subscription
+func (m *MemoryPublisher) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for subscription := range m.subscriptions {
+		close(subscription.channel)
+	}
+	m.subscriptions = make(map[*memorySubscription]bool)
+	return nil
+}
+
+func matches(pattern []string, tokens []string) bool {
+	for i, token := range pattern {
+		if token == ">" && i == len(pattern)-1 {
+			return len(tokens) > i
+		}
+		if i >= len(tokens) || (token != "*" && token != tokens[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(tokens)
+}
@@ -0,0 +1,28 @@
+package broker
+
+import (
+	"github.com/nats-io/nats.go"
+)
+
+// natsPublisher publishes to a NATS server, topics are subjects
+type natsPublisher struct {
+	connection *nats.Conn
+}
+
+// ConnectNATS connects to the NATS server at url, reconnecting if the connection is lost
+func ConnectNATS(url string, name string) (Publisher, error) {
+	connection, err := nats.Connect(url, nats.Name(name), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &natsPublisher{connection: connection}, nil
+}
+
+func (n *natsPublisher) Publish(topic string, data []byte) error {
+	return n.connection.Publish(topic, data)
+}
+
+// Close publishes the buffered messages before closing the connection
+func (n *natsPublisher) Close() error {
+	return n.connection.Drain()
+}
@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const productId = "-//gosports//gosports//EN"
+const dateTimeLayout = "20060102T150405Z" // UTC date-time, RFC 5545 section 3.3.5
+const maxLineLength = 75                  // Octets, longer lines are folded
+
+// Event is a VEVENT of a calendar, UID must be stable so calendar applications update the event instead of adding another
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Location    string
+	Description string
+	Cancelled   bool
+}
+
+var textEscaper = strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\r\n", "\\n", "\n", "\\n")
+
+// Write writes events as an RFC 5545 iCalendar named name, stamp is the DTSTAMP of every event
+func Write(w io.Writer, name string, events []Event, stamp time.Time) error {
+	b := bufio.NewWriter(w)
+	writeLine(b, "BEGIN:VCALENDAR")
+	writeLine(b, "VERSION:2.0")
+	writeLine(b, "PRODID:"+productId)
+	writeLine(b, "CALSCALE:GREGORIAN")
+	writeLine(b, "METHOD:PUBLISH")
+	writeLine(b, "X-WR-CALNAME:"+escapeText(name))
+	for _, event := range events {
+		writeLine(b, "BEGIN:VEVENT")
+		writeLine(b, "UID:"+event.UID)
+		writeLine(b, "DTSTAMP:"+formatDateTime(stamp))
+		writeLine(b, "DTSTART:"+formatDateTime(event.Start))
+		writeLine(b, "DTEND:"+formatDateTime(event.End))
+		writeLine(b, "SUMMARY:"+escapeText(event.Summary))
+		if event.Location != "" {
+			writeLine(b, "LOCATION:"+escapeText(event.Location))
+		}
+		if event.Description != "" {
+			writeLine(b, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.Cancelled {
+			writeLine(b, "STATUS:CANCELLED")
+		} else {
+			writeLine(b, "STATUS:CONFIRMED")
+		}
+		writeLine(b, "END:VEVENT")
+	}
+	writeLine(b, "END:VCALENDAR")
+	return b.Flush()
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format(dateTimeLayout)
+}
+
+func escapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+// writeLine writes a content line terminated by CRLF, folding it into lines of at most maxLineLength octets
+// without splitting UTF-8 characters. Continuation lines start with a space.
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineLength
+	for len(line) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		_, _ = w.WriteString(line[:i] + "\r\n ")
+		line = line[i:]
+		limit = maxLineLength - 1 // The leading space counts towards the length
+	}
+	_, _ = w.WriteString(line + "\r\n")
+}
@@ -0,0 +1,345 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/henrymxu/gosports/sports"
+	"github.com/ngaut/log"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Configuration of the server, loaded by Load from (in increasing order of precedence) Default, a YAML or TOML file,
+// environment variables and command line flags. Settings are overridden by the environment variable in their env tag
+// and the flag in their flag tag, settings with a secret tag are redacted when written.
+
+const fileEnv = "GOSPORTS_CONFIG" // Configuration file, if the -config flag is not set
+const redacted = "REDACTED"
+
+const MongoBackend = "mongo" // The only supported storage backend
+
+type Config struct {
+	Server   Server   `yaml:"server" toml:"server"`
+	Storage  Storage  `yaml:"storage" toml:"storage"`
+	Sports   Sports   `yaml:"sports" toml:"sports"`
+	Watch    Watch    `yaml:"watch" toml:"watch"`
+	Origins  Origins  `yaml:"origins" toml:"origins"`
+	Logging  Logging  `yaml:"logging" toml:"logging"`
+	Auth     Auth     `yaml:"auth" toml:"auth"`
+	Webhooks Webhooks `yaml:"webhooks" toml:"webhooks"`
+	Broker   Broker   `yaml:"broker" toml:"broker"`
+}
+
+type Server struct {
+	Address      string        `yaml:"address" toml:"address" env:"GOSPORTS_ADDRESS" flag:"address"`
+	GRPCAddress  string        `yaml:"grpcAddress" toml:"grpcAddress" env:"GOSPORTS_GRPC_ADDRESS" flag:"grpc-address"`
+	ReadTimeout  time.Duration `yaml:"readTimeout" toml:"readTimeout" env:"GOSPORTS_READ_TIMEOUT" flag:"read-timeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout" toml:"writeTimeout" env:"GOSPORTS_WRITE_TIMEOUT" flag:"write-timeout"`
+	TLS          TLS           `yaml:"tls" toml:"tls"`
+}
+
+// TLS is enabled for the server and the gRPC service when both files are set
+type TLS struct {
+	CertFile string `yaml:"certFile" toml:"certFile" env:"GOSPORTS_TLS_CERT_FILE" flag:"tls-cert"`
+	KeyFile  string `yaml:"keyFile" toml:"keyFile" env:"GOSPORTS_TLS_KEY_FILE" flag:"tls-key"`
+}
+
+type Storage struct {
+	Backend  string        `yaml:"backend" toml:"backend" env:"GOSPORTS_STORAGE_BACKEND" flag:"storage-backend"`
+	URI      string        `yaml:"uri" toml:"uri" env:"GOSPORTS_STORAGE_URI" flag:"storage-uri" secret:"true"`
+	TickRate time.Duration `yaml:"tickRate" toml:"tickRate" env:"GOSPORTS_STORAGE_TICK_RATE" flag:"tick-rate"`
+}
+
+// LongPollTimeout is the longest the server holds a long poll or a scoreboard stream response open,
+// server.writeTimeout must exceed it by writeTimeoutMargin so the response can still be written
+const LongPollTimeout = 12 * time.Second
+const writeTimeoutMargin = 3 * time.Second
+
+type Sports struct {
+	Enabled []string         `yaml:"enabled" toml:"enabled" env:"GOSPORTS_SPORTS" flag:"sports"`
+	Limits  map[string]Limit `yaml:"limits" toml:"limits"` // sports.DefaultUpstreamLimit for sports that are not set
+}
+
+// Limit is the rate of requests a sport may make to its league's api
+type Limit struct {
+	RequestsPerSecond float64 `yaml:"requestsPerSecond" toml:"requestsPerSecond"`
+	Burst             int     `yaml:"burst" toml:"burst"`
+}
+
+type Watch struct {
+	ScheduleCheck    time.Duration   `yaml:"scheduleCheck" toml:"scheduleCheck" env:"GOSPORTS_SCHEDULE_CHECK" flag:"schedule-check"`
+	GameLiveCheck    time.Duration   `yaml:"gameLiveCheck" toml:"gameLiveCheck" env:"GOSPORTS_GAME_LIVE_CHECK" flag:"game-live-check"`
+	StandingsRefresh []time.Duration `yaml:"standingsRefresh" toml:"standingsRefresh" env:"GOSPORTS_STANDINGS_REFRESH"`
+}
+
+// Origins (besides the server's host) allowed to open websockets and make CORS requests,
+// exact (https://example.com), wildcard subdomain (https://*.example.com) or any (*)
+type Origins struct {
+	Allowed    []string `yaml:"allowed" toml:"allowed" env:"GOSPORTS_ORIGINS" flag:"origins"`
+	AllowEmpty bool     `yaml:"allowEmpty" toml:"allowEmpty" env:"GOSPORTS_ALLOW_EMPTY_ORIGIN" flag:"allow-empty-origin"`
+}
+
+type Logging struct {
+	Level string `yaml:"level" toml:"level" env:"GOSPORTS_LOG_LEVEL" flag:"log-level"`
+	File  string `yaml:"file" toml:"file" env:"GOSPORTS_LOG_FILE" flag:"log-file"` // Standard error if not set
+}
+
+// Auth secrets are only read from the file and the environment, so they are not visible in the process list
+type Auth struct {
+	AdminKey  string `yaml:"adminKey" toml:"adminKey" env:"GOSPORTS_ADMIN_KEY" secret:"true"`    // Admin api key used to create the first keys
+	TokenKeys string `yaml:"tokenKeys" toml:"tokenKeys" env:"GOSPORTS_TOKEN_KEYS" secret:"true"` // Token signing keys `kid:secret,kid:secret`, the first one signs new tokens
+}
+
+type Webhooks struct {
+	Timeout time.Duration `yaml:"timeout" toml:"timeout" env:"GOSPORTS_WEBHOOK_TIMEOUT" flag:"webhook-timeout"` // Timeout of each delivery attempt
+}
+
+type Broker struct {
+	NATSURL  string `yaml:"natsUrl" toml:"natsUrl" env:"GOSPORTS_NATS_URL" flag:"nats-url" secret:"true"`   // Game updates are not published if not set
+	Encoding string `yaml:"encoding" toml:"encoding" env:"GOSPORTS_BROKER_ENCODING" flag:"broker-encoding"` // json, msgpack or protobuf
+	Mode     string `yaml:"mode" toml:"mode" env:"GOSPORTS_BROKER_MODE" flag:"broker-mode"`                 // full or patch
+}
+
+// Default returns the configuration used for the settings that are not set
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Address:      "localhost:8080",
+			GRPCAddress:  "localhost:9090",
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+		},
+		Storage: Storage{
+			Backend:  MongoBackend,
+			URI:      "mongodb://localhost:27017",
+			TickRate: 5 * time.Second,
+		},
+		Sports: Sports{
+			Enabled: []string{"nhl", "nba"},
+			Limits: map[string]Limit{
+				"nhl": {RequestsPerSecond: 2, Burst: 5},
+				"nba": {RequestsPerSecond: 1, Burst: 3},
+			},
+		},
+		Watch: Watch{
+			ScheduleCheck:    1 * time.Hour,
+			GameLiveCheck:    20 * time.Second,
+			StandingsRefresh: []time.Duration{0, 2 * time.Minute, 5 * time.Minute},
+		},
+		Origins: Origins{
+			Allowed: []string{},
+		},
+		Logging: Logging{
+			Level: "info",
+		},
+		Webhooks: Webhooks{
+			Timeout: 10 * time.Second,
+		},
+		Broker: Broker{
+			Encoding: "json",
+			Mode:     "full",
+		},
+	}
+}
+
+// Load registers the -config flag and a flag for every setting with a flag tag on flags, parses args,
+// and returns the validated configuration
+func Load(flags *flag.FlagSet, args []string) (*Config, error) {
+	path := flags.String("config", os.Getenv(fileEnv), "Configuration file, .yaml, .yml or .toml")
+	overrides := make(map[string]*override)
+	visit(reflect.ValueOf(&Config{}).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if name, ok := field.Tag.Lookup("flag"); ok {
+			overrides[name] = &override{isBool: field.Type.Kind() == reflect.Bool}
+			flags.Var(overrides[name], name, fmt.Sprintf("Overrides %s", field.Tag.Get("env")))
+		}
+	})
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := Default()
+	if *path != "" {
+		if err := config.ReadFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	var errs []string
+	visit(reflect.ValueOf(config).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if name, ok := field.Tag.Lookup("env"); ok {
+			if raw, ok := os.LookupEnv(name); ok {
+				if err := set(value, raw); err != nil {
+					errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+				}
+			}
+		}
+		if name, ok := field.Tag.Lookup("flag"); ok && overrides[name].isSet {
+			if err := set(value, overrides[name].raw); err != nil {
+				errs = append(errs, fmt.Sprintf("-%s: %s", name, err))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n  %s", strings.Join(errs, "\n  "))
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// UpstreamLimits returns the upstream limits of the sports, by sport name
+func (c *Config) UpstreamLimits() map[string]sports.UpstreamLimit {
+	limits := make(map[string]sports.UpstreamLimit, len(c.Sports.Limits))
+	for sport, limit := range c.Sports.Limits {
+		limits[sport] = sports.UpstreamLimit{RequestsPerSecond: limit.RequestsPerSecond, Burst: limit.Burst}
+	}
+	return limits
+}
+
+// ConfigureLogging sets the level and output of the log
+func (c *Config) ConfigureLogging() error {
+	log.SetLevelByString(c.Logging.Level)
+	if c.Logging.File != "" {
+		return log.SetOutputByName(c.Logging.File)
+	}
+	return nil
+}
+
+// ReadFile reads the settings set in the YAML or TOML file at path (by extension) into c
+func (c *Config) ReadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	limits := c.Sports.Limits
+	c.Sports.Limits = nil // Strict YAML decoding rejects keys that are already set
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, c)
+	case ".toml":
+		var metadata toml.MetaData
+		metadata, err = toml.Decode(string(data), c)
+		if err == nil && len(metadata.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown settings %v", metadata.Undecoded())
+		}
+	default:
+		c.Sports.Limits = limits
+		return fmt.Errorf("configuration file %s is not .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading configuration file %s: %s", path, err)
+	}
+	if c.Sports.Limits == nil {
+		c.Sports.Limits = make(map[string]Limit)
+	}
+	for sport, limit := range limits {
+		if _, ok := c.Sports.Limits[sport]; !ok {
+			c.Sports.Limits[sport] = limit
+		}
+	}
+	return nil
+}
+
+// Write writes c as YAML to w with its secrets redacted
+func (c *Config) Write(w io.Writer) error {
+	copied := *c
+	visit(reflect.ValueOf(&copied).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") == "true" && value.String() != "" {
+			value.SetString(redacted)
+		}
+	})
+	data, err := yaml.Marshal(&copied)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, bytes.NewReader(data))
+	return err
+}
+
+// visit calls fn with every setting (field that is not a section) of the sections of config
+func visit(config reflect.Value, fn func(field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < config.NumField(); i++ {
+		field := config.Type().Field(i)
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			visit(config.Field(i), fn)
+		} else {
+			fn(field, config.Field(i))
+		}
+	}
+}
+
+// set parses raw into the setting value, lists are comma separated
+func set(value reflect.Value, raw string) error {
+	switch value.Interface().(type) {
+	case string:
+		value.SetString(raw)
+	case bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(parsed)
+	case time.Duration:
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(parsed))
+	case []string:
+		value.Set(reflect.ValueOf(split(raw)))
+	case []time.Duration:
+		parsed := make([]time.Duration, 0)
+		for _, part := range split(raw) {
+			duration, err := time.ParseDuration(part)
+			if err != nil {
+				return err
+			}
+			parsed = append(parsed, duration)
+		}
+		value.Set(reflect.ValueOf(parsed))
+	default:
+		return fmt.Errorf("unsupported setting type %s", value.Type())
+	}
+	return nil
+}
+
+func split(raw string) []string {
+	parts := make([]string, 0)
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// override is the value of a setting's flag, applied by Load after the file and the environment
+type override struct {
+	raw    string
+	isSet  bool
+	isBool bool
+}
+
+func (o *override) String() string {
+	return o.raw
+}
+
+func (o *override) Set(raw string) error {
+	o.raw = raw
+	o.isSet = true
+	return nil
+}
+
+// IsBoolFlag allows boolean settings to be set without a value (-allow-empty-origin)
+func (o *override) IsBoolFlag() bool {
+	return o.isBool
+}
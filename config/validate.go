@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"github.com/henrymxu/gosports/sports"
+	"github.com/henrymxu/gosports/websocket"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+var logLevels = []string{"debug", "info", "warn", "error", "fatal"}
+
+// Validate checks every setting of c, returning all of the invalid settings at once
+func (c *Config) Validate() error {
+	var errs []string
+	invalid := func(setting string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf("%s: %s", setting, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Address); err != nil {
+		invalid("server.address", "%s is not a host:port address", c.Server.Address)
+	}
+	if _, _, err := net.SplitHostPort(c.Server.GRPCAddress); err != nil {
+		invalid("server.grpcAddress", "%s is not a host:port address", c.Server.GRPCAddress)
+	}
+	if c.Server.ReadTimeout <= 0 {
+		invalid("server.readTimeout", "must be positive")
+	}
+	if c.Server.WriteTimeout < LongPollTimeout+writeTimeoutMargin {
+		invalid("server.writeTimeout", "must be at least %s, long polls are held open for up to %s",
+			LongPollTimeout+writeTimeoutMargin, LongPollTimeout)
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		invalid("server.tls", "certFile and keyFile must both be set")
+	}
+	if _, err := os.Stat(c.Server.TLS.CertFile); c.Server.TLS.CertFile != "" && err != nil {
+		invalid("server.tls.certFile", "%s", err)
+	}
+	if _, err := os.Stat(c.Server.TLS.KeyFile); c.Server.TLS.KeyFile != "" && err != nil {
+		invalid("server.tls.keyFile", "%s", err)
+	}
+
+	if c.Storage.Backend != MongoBackend {
+		invalid("storage.backend", "%s is not supported, must be %s", c.Storage.Backend, MongoBackend)
+	} else if !strings.HasPrefix(c.Storage.URI, "mongodb://") && !strings.HasPrefix(c.Storage.URI, "mongodb+srv://") {
+		invalid("storage.uri", "must be a mongodb:// or mongodb+srv:// uri")
+	}
+	if c.Storage.TickRate <= 0 {
+		invalid("storage.tickRate", "must be positive")
+	}
+
+	if len(c.Sports.Enabled) == 0 {
+		invalid("sports.enabled", "at least one of %s must be enabled", strings.Join(sports.SportNames, ", "))
+	}
+	enabled := make(map[string]bool)
+	for _, sport := range c.Sports.Enabled {
+		if !contains(sports.SportNames, sport) {
+			invalid("sports.enabled", "%s is not one of %s", sport, strings.Join(sports.SportNames, ", "))
+		} else if enabled[sport] {
+			invalid("sports.enabled", "%s is enabled twice", sport)
+		}
+		enabled[sport] = true
+	}
+	limited := make([]string, 0, len(c.Sports.Limits))
+	for sport := range c.Sports.Limits {
+		limited = append(limited, sport)
+	}
+	sort.Strings(limited)
+	for _, sport := range limited {
+		limit := c.Sports.Limits[sport]
+		if !contains(sports.SportNames, sport) {
+			invalid("sports.limits", "%s is not one of %s", sport, strings.Join(sports.SportNames, ", "))
+		}
+		if limit.RequestsPerSecond <= 0 || limit.Burst < 1 {
+			invalid("sports.limits."+sport, "requestsPerSecond must be positive and burst at least 1")
+		}
+	}
+
+	if c.Watch.ScheduleCheck <= 0 {
+		invalid("watch.scheduleCheck", "must be positive")
+	}
+	if c.Watch.GameLiveCheck <= 0 {
+		invalid("watch.gameLiveCheck", "must be positive")
+	}
+	for _, delay := range c.Watch.StandingsRefresh {
+		if delay < 0 {
+			invalid("watch.standingsRefresh", "%s must not be negative", delay)
+		}
+	}
+
+	for _, origin := range c.Origins.Allowed {
+		if origin == "*" {
+			continue
+		}
+		if originUrl, err := url.Parse(origin); err != nil || originUrl.Scheme == "" || originUrl.Host == "" {
+			invalid("origins.allowed", "%s is not *, an origin or a wildcard subdomain origin", origin)
+		}
+	}
+
+	if !contains(logLevels, c.Logging.Level) {
+		invalid("logging.level", "%s is not one of %s", c.Logging.Level, strings.Join(logLevels, ", "))
+	}
+
+	if c.Webhooks.Timeout <= 0 {
+		invalid("webhooks.timeout", "must be positive")
+	}
+
+	if c.Broker.NATSURL != "" {
+		if brokerUrl, err := url.Parse(c.Broker.NATSURL); err != nil || brokerUrl.Host == "" {
+			invalid("broker.natsUrl", "must be a nats:// url")
+		}
+	}
+	if websocket.GetEncoding(c.Broker.Encoding) == nil {
+		invalid("broker.encoding", "%s is not json, msgpack or protobuf", c.Broker.Encoding)
+	}
+	if c.Broker.Mode != websocket.FullMode && c.Broker.Mode != websocket.PatchMode {
+		invalid("broker.mode", "%s is not %s or %s", c.Broker.Mode, websocket.FullMode, websocket.PatchMode)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
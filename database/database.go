@@ -2,9 +2,12 @@ package database
 
 import (
 	"context"
+	"errors"
 	"github.com/mongodb/mongo-go-driver/bson"
 )
 
+var ErrNotFound = errors.New("document not found")
+
 type client interface {
 	Initialize(address string)
 	Database(database string) Database
@@ -17,6 +20,10 @@ type Database interface {
 type Collection interface {
 	InsertGameSnapshot(ctx context.Context, snapshot interface{})
 	WatchGame(ctx context.Context) (cursor Cursor, err error)
+	FindDocument(ctx context.Context, id string, document interface{}) error // Returns ErrNotFound if there is no document with id
+	FindDocuments(ctx context.Context, documents interface{}) error          // Decodes every document into documents, a pointer to a slice
+	ReplaceDocument(ctx context.Context, id string, document interface{}) error
+	DeleteDocument(ctx context.Context, id string) error
 }
 
 type Cursor interface {
@@ -8,7 +8,9 @@ import (
 	"time"
 )
 
-const FormatDatabaseName = "%sGameData" //Example NHLGameData or NBAGameData
+const FormatDatabaseName = "%sGameData"     //Example NHLGameData or NBAGameData
+const ServerDatabaseName = "GoSportsServer" // Data that does not belong to a sport, such as api keys
+const DefaultTickRate = 5 * time.Second     // Rate of the ticker if none is given
 
 type Server struct {
 	Client              client // TODO convert this to a interface?
@@ -18,7 +20,7 @@ type Server struct {
 	tickChannels        map[chan bool]bool // This needs concurrency support
 }
 
-func CreateDatabaseServer(client client) *Server {
+func CreateDatabaseServer(client client, tickRate time.Duration) *Server {
 	server := Server{
 		Client:            client,
 		tickChannels:        make(map[chan bool]bool),
@@ -26,7 +28,7 @@ func CreateDatabaseServer(client client) *Server {
 		completedChannel:    make(chan int),
 		currentLiveChannels: make(map[int]bool),
 	}
-	go server.initializeTicker(5)
+	go server.initializeTicker(tickRate)
 	return &server
 }
 
@@ -35,6 +37,11 @@ func (d *Server) GetDatabase(sport string) Database {
 	return d.Client.Database(fmt.Sprintf(FormatDatabaseName, sport))
 }
 
+// Retrieve the database for data that does not belong to a sport
+func (d *Server) GetServerDatabase() Database {
+	return d.Client.Database(ServerDatabaseName)
+}
+
 // Creates a new tick channel that replicates the database tick channel
 func (d *Server) GetDatabaseTickChannel() <-chan bool {
 	channel := make(chan bool)
@@ -62,13 +69,13 @@ func (d *Server) WatchCollection(collection Collection) <-chan bson.Raw {
 	return watchChannel
 }
 
-// initializeTicker creates a channel that is sent to every rate seconds
+// initializeTicker creates a channel that is sent to every rate
 func (d *Server) initializeTicker(rate time.Duration) {
 	log.Debugf("Initializing DatabaseTicker with rate %v", rate)
 	if rate == 0 {
-		rate = 5
+		rate = DefaultTickRate
 	}
-	ticker := time.NewTicker(rate * time.Second)
+	ticker := time.NewTicker(rate)
 	for {
 		<-ticker.C
 		for channel := range d.tickChannels {
@@ -4,7 +4,9 @@ import (
 	"context"
 	"github.com/mongodb/mongo-go-driver/bson"
 	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/mongodb/mongo-go-driver/mongo/replaceopt"
 	"github.com/ngaut/log"
+	"reflect"
 )
 
 type MongoClient struct {
@@ -68,6 +70,45 @@ func (c *MongoCollection) WatchGame(ctx context.Context) (Cursor Cursor, err err
 	return &mongoCursor, err
 }
 
+// FindDocument decodes the document with _id id into document
+func (c *MongoCollection) FindDocument(ctx context.Context, id string, document interface{}) error {
+	err := c.FindOne(ctx, bson.NewDocument(bson.EC.String("_id", id))).Decode(document)
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	return err
+}
+
+// FindDocuments decodes every document of the collection into documents, a pointer to a slice
+func (c *MongoCollection) FindDocuments(ctx context.Context, documents interface{}) error {
+	cursor, err := c.Find(ctx, bson.NewDocument())
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	slice := reflect.ValueOf(documents).Elem()
+	for cursor.Next(ctx) {
+		document := reflect.New(slice.Type().Elem())
+		if err := cursor.Decode(document.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, document.Elem()))
+	}
+	return cursor.Err()
+}
+
+// ReplaceDocument replaces the document with _id id, inserting it if it does not exist
+func (c *MongoCollection) ReplaceDocument(ctx context.Context, id string, document interface{}) error {
+	_, err := c.ReplaceOne(ctx, bson.NewDocument(bson.EC.String("_id", id)), document, replaceopt.Upsert(true))
+	return err
+}
+
+// DeleteDocument deletes the document with _id id
+func (c *MongoCollection) DeleteDocument(ctx context.Context, id string) error {
+	_, err := c.DeleteOne(ctx, bson.NewDocument(bson.EC.String("_id", id)))
+	return err
+}
+
 func (c *MongoCursor) Close() {
 	_ = c.Cursor.Close(context.Background())
 }
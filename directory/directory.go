@@ -0,0 +1,192 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/henrymxu/gosports/database"
+	"github.com/henrymxu/gosports/sports"
+	"github.com/ngaut/log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const TeamsCollectionName = "teams"
+const RostersCollectionName = "rosters"
+const PlayersCollectionName = "players"
+const PlayerStatsCollectionName = "playerStats"
+
+// How long stored documents are used before they are requested again
+const teamsDuration = 24 * time.Hour
+const rosterDuration = 6 * time.Hour
+const playerDuration = 24 * time.Hour
+const playerStatsDuration = 1 * time.Hour
+
+const rosterRefreshInterval = 1 * time.Hour // How often RefreshRosters requests the rosters older than rosterDuration
+
+const maxSearchResults = 25
+
+// document is a result stored in the database, Content is the JSON of the result's content
+type document struct {
+	Id      string    `bson:"_id"`
+	Content string    `bson:"content"`
+	Updated time.Time `bson:"updated"`
+}
+
+// Directory stores the teams, rosters and players of every sport in the sport's database,
+// requesting them from the sport when they are missing or out of date
+type Directory struct {
+	databaseServer *database.Server
+}
+
+func CreateDirectory(databaseServer *database.Server) *Directory {
+	return &Directory{
+		databaseServer: databaseServer,
+	}
+}
+
+// Teams returns the teams of sport in result["content"], sport must support sports.Directory
+func (d *Directory) Teams(sport sports.Sport) map[string]interface{} {
+	return d.get(sport, TeamsCollectionName, TeamsCollectionName, teamsDuration, func() map[string]interface{} {
+		return sport.(sports.Directory).Teams(nil)
+	})
+}
+
+// Roster returns the players of the team with teamId (the league's id) in result["content"], sport must support sports.Directory
+func (d *Directory) Roster(sport sports.Sport, teamId string) map[string]interface{} {
+	return d.get(sport, RostersCollectionName, teamId, rosterDuration, func() map[string]interface{} {
+		return sport.(sports.Directory).Roster(url.Values{"teamId": {teamId}})
+	})
+}
+
+// Player returns the profile of the player with playerId in result["content"], sport must support sports.Scout
+func (d *Directory) Player(sport sports.Sport, playerId string) map[string]interface{} {
+	return d.get(sport, PlayersCollectionName, playerId, playerDuration, func() map[string]interface{} {
+		return sport.(sports.Scout).Player(url.Values{"playerId": {playerId}})
+	})
+}
+
+// PlayerStats returns the season and career stats of the player with playerId in result["content"], sport must support sports.Scout
+func (d *Directory) PlayerStats(sport sports.Sport, playerId string) map[string]interface{} {
+	return d.get(sport, PlayerStatsCollectionName, playerId, playerStatsDuration, func() map[string]interface{} {
+		return sport.(sports.Scout).PlayerStats(url.Values{"playerId": {playerId}})
+	})
+}
+
+// RefreshRosters keeps the rosters of every team of the sports that support sports.Directory stored,
+// so that searches never wait for the league's api
+// Goroutine function
+func (d *Directory) RefreshRosters(sportsList []sports.Sport) {
+	ticker := time.NewTicker(rosterRefreshInterval)
+	for ; true; <-ticker.C {
+		for _, sport := range sportsList {
+			if !sports.SupportsDirectory(sport) {
+				continue
+			}
+			teams, _ := d.Teams(sport)["content"].([]map[string]interface{})
+			for _, team := range teams {
+				d.Roster(sport, fmt.Sprint(team["teamId"]))
+			}
+		}
+	}
+}
+
+// SearchPlayers returns the players of every roster whose name contains name (case insensitive) in result["content"],
+// sport must support sports.Directory. Only the rosters stored by RefreshRosters are searched.
+func (d *Directory) SearchPlayers(sport sports.Sport, name string) map[string]interface{} {
+	teamsResult := d.Teams(sport)
+	if status := sports.UpstreamStatus(teamsResult); teamsResult == nil || status < 200 || status >= 300 {
+		return teamsResult
+	}
+	name = strings.ToLower(name)
+	players := make([]map[string]interface{}, 0)
+	teams, _ := teamsResult["content"].([]map[string]interface{})
+	searched := 0
+	for _, team := range teams {
+		stored := d.find(sport, RostersCollectionName, fmt.Sprint(team["teamId"]))
+		if stored == nil { // Not refreshed yet
+			continue
+		}
+		searched++
+		roster, _ := buildResultFromDocument(stored)["content"].([]map[string]interface{})
+		for _, player := range roster {
+			if !strings.Contains(strings.ToLower(fmt.Sprint(player["name"])), name) {
+				continue
+			}
+			match := map[string]interface{}{"team": team["id"]}
+			for key, value := range player {
+				match[key] = value
+			}
+			players = append(players, match)
+			if len(players) == maxSearchResults {
+				return buildSearchResult(players)
+			}
+		}
+	}
+	if searched == 0 && len(teams) > 0 { // Rosters are still being requested
+		return map[string]interface{}{"metadata": map[string]interface{}{"status": http.StatusServiceUnavailable}}
+	}
+	return buildSearchResult(players)
+}
+
+func buildSearchResult(players []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"content": players, "metadata": map[string]interface{}{"status": http.StatusOK}}
+}
+
+// get returns the stored document with id if it is newer than duration, otherwise the result of request is stored and returned.
+// The stored document is also returned if request fails.
+func (d *Directory) get(sport sports.Sport, collectionName string, id string, duration time.Duration,
+	request func() map[string]interface{}) map[string]interface{} {
+	stored := d.find(sport, collectionName, id)
+	if stored != nil && time.Since(stored.Updated) < duration {
+		return buildResultFromDocument(stored)
+	}
+	result := request()
+	if status := sports.UpstreamStatus(result); result == nil || status < 200 || status >= 300 {
+		if stored != nil {
+			return buildResultFromDocument(stored)
+		}
+		return result
+	}
+	b, _ := json.Marshal(result["content"])
+	stored = &document{Id: id, Content: string(b), Updated: time.Now()}
+	collection := d.databaseServer.GetDatabase(sport.Name()).Collection(collectionName)
+	if err := collection.ReplaceDocument(context.Background(), id, stored); err != nil {
+		log.Errorf("Error storing %s %s %s: %s", sport.Name(), collectionName, id, err)
+	}
+	return result
+}
+
+// find returns the stored document with id, or nil if it is not stored
+func (d *Directory) find(sport sports.Sport, collectionName string, id string) *document {
+	collection := d.databaseServer.GetDatabase(sport.Name()).Collection(collectionName)
+	stored := document{}
+	err := collection.FindDocument(context.Background(), id, &stored)
+	if err != nil {
+		if err != database.ErrNotFound {
+			log.Errorf("Error finding %s %s %s: %s", sport.Name(), collectionName, id, err)
+		}
+		return nil
+	}
+	return &stored
+}
+
+func buildResultFromDocument(stored *document) map[string]interface{} {
+	var content interface{}
+	if err := json.Unmarshal([]byte(stored.Content), &content); err != nil {
+		log.Errorf("Error decoding stored %s: %s", stored.Id, err)
+	}
+	if list, ok := content.([]interface{}); ok { // Lists are built as []map[string]interface{} by the sports
+		objects := make([]map[string]interface{}, 0, len(list))
+		for _, item := range list {
+			if object, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, object)
+			}
+		}
+		content = objects
+	}
+	metadata := map[string]interface{}{"status": http.StatusOK, "updated": sports.CreateDetailedStringFromDate(stored.Updated)}
+	return map[string]interface{}{"content": content, "metadata": metadata}
+}
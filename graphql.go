@@ -0,0 +1,851 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/henrymxu/gosports/auth"
+	"github.com/henrymxu/gosports/broker"
+	"github.com/henrymxu/gosports/sports"
+	"github.com/henrymxu/gosports/watch"
+	"github.com/ngaut/log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const maxGraphQLRequestSize = 1 << 20 // Bytes
+
+// maxGraphQLUpstreamFields limits the fields of an operation that request a league's api (schedule, game, boxScore,
+// teams, team, players, player, playByPlay, roster and stats), so one operation cannot use up a sport's upstream limit
+const maxGraphQLUpstreamFields = 20
+
+const graphQLCostContextKey = contextKey("graphQLCost")
+
+// graphQLCost counts the upstream fields resolved by an operation
+type graphQLCost struct {
+	fields int
+	free   int // Fields covered by the request that executes the operation
+	mutex  sync.Mutex
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// handleGraphQL executes GraphQL queries sent as GET (query, operationName and variables queries) or POST (JSON body).
+// Subscriptions are served by handleGraphQLSubscriptions.
+func (s *server) handleGraphQL(schema graphql.Schema) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := graphQLRequest{}
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequestSize)).Decode(&request); err != nil {
+				logHttpError(w, &httpError{
+					http.StatusBadRequest,
+					fmt.Sprintf("Invalid GraphQL request: %s", err),
+				})
+				return
+			}
+		} else {
+			query := r.URL.Query()
+			request.Query = query.Get("query")
+			request.OperationName = query.Get("operationName")
+			if variables := query.Get("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+					logHttpError(w, &httpError{
+						http.StatusBadRequest,
+						"Invalid {variables} query",
+					})
+					return
+				}
+			}
+		}
+		if request.Query == "" {
+			logHttpError(w, &httpError{
+				http.StatusBadRequest,
+				"Missing required GraphQL query",
+			})
+			return
+		}
+		if graphQLOperation(request) == ast.OperationTypeSubscription {
+			logHttpError(w, &httpError{
+				http.StatusBadRequest,
+				"Subscriptions are only served over websockets, see /graphql/ws",
+			})
+			return
+		}
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  request.Query,
+			VariableValues: request.Variables,
+			OperationName:  request.OperationName,
+			Context:        withGraphQLCost(r.Context(), 1),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
+
+// withGraphQLCost returns a context that counts the upstream fields of the operation executed with it,
+// the first free fields do not consume the api key's quota
+func withGraphQLCost(ctx context.Context, free int) context.Context {
+	return context.WithValue(ctx, graphQLCostContextKey, &graphQLCost{free: free})
+}
+
+// chargeGraphQLUpstream counts an upstream field of the operation executed with ctx, failing past
+// maxGraphQLUpstreamFields. Every field past the free ones consumes a request of the api key's quota.
+func (s *server) chargeGraphQLUpstream(ctx context.Context) error {
+	cost, ok := ctx.Value(graphQLCostContextKey).(*graphQLCost)
+	if !ok {
+		return nil
+	}
+	cost.mutex.Lock()
+	cost.fields++
+	fields := cost.fields
+	cost.mutex.Unlock()
+	if fields > maxGraphQLUpstreamFields {
+		return fmt.Errorf("Operations can resolve at most %d schedule, game, box score, team, roster or player fields",
+			maxGraphQLUpstreamFields)
+	}
+	if apiKey, _ := ctx.Value(apiKeyContextKey).(*auth.APIKey); apiKey != nil && fields > cost.free {
+		if allowed, _ := s.quotas.AllowRequest(apiKey); !allowed {
+			return errors.New("Request rate limit reached")
+		}
+	}
+	return nil
+}
+
+// graphQLOperation returns the type (query, mutation or subscription) of the operation a request executes,
+// or an empty string if the request is invalid
+func graphQLOperation(request graphQLRequest) string {
+	document, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	if err != nil {
+		return ""
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if request.OperationName == "" || (operation.Name != nil && operation.Name.Value == request.OperationName) {
+			return operation.Operation
+		}
+	}
+	return ""
+}
+
+// Arbitrary JSON, for models that differ between sports (box scores and player stats)
+var graphQLJSON = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Arbitrary JSON value, its fields depend on the sport",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return nil
+	},
+})
+
+var graphQLGameState = graphql.NewEnum(graphql.EnumConfig{
+	Name: "GameState",
+	Values: graphql.EnumValueConfigMap{
+		"PREVIEW":      &graphql.EnumValueConfig{Value: sports.Preview},
+		"LIVE":         &graphql.EnumValueConfig{Value: sports.Live},
+		"INTERMISSION": &graphql.EnumValueConfig{Value: sports.Intermission},
+		"COMPLETE":     &graphql.EnumValueConfig{Value: sports.Complete},
+		"POSTPONED":    &graphql.EnumValueConfig{Value: sports.Postponed},
+	},
+})
+
+// graphQLSchema builds the schema of the GraphQL api. Models are the maps returned by the sports, normalized with
+// normalizeGraphQL so that the default resolvers can read them.
+func (s *server) graphQLSchema() (graphql.Schema, error) {
+	participant := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Participant",
+		Fields: graphql.Fields{
+			"role":     &graphql.Field{Type: graphql.String},
+			"playerId": &graphql.Field{Type: graphql.ID},
+			"name":     &graphql.Field{Type: graphql.String},
+			"number":   &graphql.Field{Type: graphql.String},
+			"team":     &graphql.Field{Type: graphql.String},
+			"teamId":   &graphql.Field{Type: graphql.String},
+		},
+	})
+	play := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Play",
+		Fields: graphql.Fields{
+			"description": &graphql.Field{Type: graphql.String},
+			"typeId":      &graphql.Field{Type: graphql.String},
+			"teamId":      &graphql.Field{Type: graphql.String},
+			"periodTime":  &graphql.Field{Type: graphql.String},
+			"dateTime":    &graphql.Field{Type: graphql.String},
+			"coordinates": &graphql.Field{Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "Coordinates",
+				Fields: graphql.Fields{
+					"x": &graphql.Field{Type: graphql.Float},
+					"y": &graphql.Field{Type: graphql.Float},
+				},
+			})},
+			"participants": &graphql.Field{Type: graphql.NewList(participant)},
+		},
+	})
+	score := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Score",
+		Fields: graphql.Fields{
+			"home": &graphql.Field{Type: graphql.Int},
+			"away": &graphql.Field{Type: graphql.Int},
+		},
+	})
+	event := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Event",
+		Fields: graphql.Fields{
+			"type":         &graphql.Field{Type: graphql.String},
+			"period":       &graphql.Field{Type: graphql.Int},
+			"periodTime":   &graphql.Field{Type: graphql.String},
+			"description":  &graphql.Field{Type: graphql.String},
+			"teamId":       &graphql.Field{Type: graphql.String},
+			"score":        &graphql.Field{Type: score},
+			"participants": &graphql.Field{Type: graphql.NewList(participant)},
+		},
+	})
+	gameTeam := graphql.NewObject(graphql.ObjectConfig{
+		Name: "GameTeam",
+		Fields: graphql.Fields{
+			"name":  &graphql.Field{Type: graphql.String},
+			"score": &graphql.Field{Type: graphql.Int},
+			"shots": &graphql.Field{Type: graphql.Int},
+		},
+	})
+	game := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Game",
+		Fields: graphql.Fields{
+			"status": &graphql.Field{Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "GameStatus",
+				Fields: graphql.Fields{
+					"period":              &graphql.Field{Type: graphql.Int},
+					"periodTimeRemaining": &graphql.Field{Type: graphql.String},
+				},
+			})},
+			"home": &graphql.Field{Type: gameTeam},
+			"away": &graphql.Field{Type: gameTeam},
+		},
+	})
+	onIcePlayer := graphql.NewObject(graphql.ObjectConfig{
+		Name: "OnIcePlayer",
+		Fields: graphql.Fields{
+			"id":            &graphql.Field{Type: graphql.ID},
+			"onIceDuration": &graphql.Field{Type: graphql.Int},
+			"name":          &graphql.Field{Type: graphql.String},
+			"number":        &graphql.Field{Type: graphql.String},
+			"position":      &graphql.Field{Type: graphql.String},
+		},
+	})
+	playByPlay := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PlayByPlay",
+		Fields: graphql.Fields{
+			"game": &graphql.Field{Type: game},
+			"players": &graphql.Field{Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "Players",
+				Fields: graphql.Fields{
+					"home": &graphql.Field{Type: graphql.NewList(onIcePlayer)},
+					"away": &graphql.Field{Type: graphql.NewList(onIcePlayer)},
+				},
+			})},
+			"plays":  &graphql.Field{Type: graphql.NewList(play)},
+			"events": &graphql.Field{Type: graphql.NewList(event)},
+			"metadata": &graphql.Field{Type: graphql.NewObject(graphql.ObjectConfig{
+				Name: "Metadata",
+				Fields: graphql.Fields{
+					"state":     &graphql.Field{Type: graphQLGameState, Resolve: resolveGraphQLState},
+					"lastCheck": &graphql.Field{Type: graphql.String},
+				},
+			})},
+		},
+	})
+	scheduledTeam := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ScheduledTeam",
+		Fields: graphql.Fields{
+			"id":     &graphql.Field{Type: graphql.ID},
+			"teamId": &graphql.Field{Type: graphql.String},
+			"name":   &graphql.Field{Type: graphql.String},
+			"abbr":   &graphql.Field{Type: graphql.String},
+			"record": &graphql.Field{Type: graphql.String},
+			"score":  &graphql.Field{Type: graphql.Int},
+		},
+	})
+	scheduledGame := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ScheduledGame",
+		Fields: graphql.Fields{
+			"sport":  &graphql.Field{Type: graphql.String},
+			"id":     &graphql.Field{Type: graphql.ID},
+			"date":   &graphql.Field{Type: graphql.String},
+			"status": &graphql.Field{Type: graphql.String},
+			"state": &graphql.Field{
+				Type: graphQLGameState,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					source := p.Source.(map[string]interface{})
+					sport, err := s.graphQLSport(source["sport"])
+					if err != nil {
+						return nil, err
+					}
+					statusCode, _ := source["statusCode"].(int64)
+					return sport.ParseScheduleState(int(statusCode)), nil
+				},
+			},
+			"period": &graphql.Field{Type: graphql.Int},
+			"time":   &graphql.Field{Type: graphql.String},
+			"venue":  &graphql.Field{Type: graphql.String},
+			"home":   &graphql.Field{Type: scheduledTeam},
+			"away":   &graphql.Field{Type: scheduledTeam},
+			"playByPlay": &graphql.Field{
+				Type:        playByPlay,
+				Description: "Latest play by play, with every play so far if the game is being watched",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					source := p.Source.(map[string]interface{})
+					return s.resolveGraphQLPlayByPlay(source["sport"], source["id"])
+				},
+			},
+			"boxScore": &graphql.Field{
+				Type: graphQLJSON,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					source := p.Source.(map[string]interface{})
+					return s.resolveGraphQLBoxScore(source["sport"], source["id"])
+				},
+			},
+		},
+	})
+	rosterPlayer := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RosterPlayer",
+		Fields: graphql.Fields{
+			"playerId": &graphql.Field{Type: graphql.ID},
+			"name":     &graphql.Field{Type: graphql.String},
+			"number":   &graphql.Field{Type: graphql.String},
+			"position": &graphql.Field{Type: graphql.String},
+			"team":     &graphql.Field{Type: graphql.ID, Description: "Only set for search results"},
+		},
+	})
+	team := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Team",
+		Fields: graphql.Fields{
+			"sport":      &graphql.Field{Type: graphql.String},
+			"id":         &graphql.Field{Type: graphql.ID},
+			"teamId":     &graphql.Field{Type: graphql.String},
+			"name":       &graphql.Field{Type: graphql.String},
+			"abbr":       &graphql.Field{Type: graphql.String},
+			"location":   &graphql.Field{Type: graphql.String},
+			"nickname":   &graphql.Field{Type: graphql.String},
+			"conference": &graphql.Field{Type: graphql.String},
+			"division":   &graphql.Field{Type: graphql.String},
+			"venue":      &graphql.Field{Type: graphql.String},
+			"roster": &graphql.Field{
+				Type: graphql.NewList(rosterPlayer),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					source := p.Source.(map[string]interface{})
+					sport, err := s.graphQLSport(source["sport"])
+					if err != nil {
+						return nil, err
+					}
+					teamId := fmt.Sprint(source["teamId"])
+					return resolveGraphQLContent(s.directory.Roster(sport, teamId), fmt.Sprintf("%s roster of team %s", sport.Name(), source["id"]))
+				},
+			},
+		},
+	})
+	player := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Player",
+		Fields: graphql.Fields{
+			"sport":         &graphql.Field{Type: graphql.String},
+			"playerId":      &graphql.Field{Type: graphql.ID},
+			"name":          &graphql.Field{Type: graphql.String},
+			"firstName":     &graphql.Field{Type: graphql.String},
+			"lastName":      &graphql.Field{Type: graphql.String},
+			"number":        &graphql.Field{Type: graphql.String},
+			"position":      &graphql.Field{Type: graphql.String},
+			"team":          &graphql.Field{Type: graphql.ID},
+			"teamName":      &graphql.Field{Type: graphql.String},
+			"birthDate":     &graphql.Field{Type: graphql.String},
+			"birthPlace":    &graphql.Field{Type: graphql.String},
+			"nationality":   &graphql.Field{Type: graphql.String},
+			"country":       &graphql.Field{Type: graphql.String},
+			"college":       &graphql.Field{Type: graphql.String},
+			"height":        &graphql.Field{Type: graphql.String},
+			"weight":        &graphql.Field{Type: graphql.Int},
+			"yearsPro":      &graphql.Field{Type: graphql.Int},
+			"active":        &graphql.Field{Type: graphql.Boolean},
+			"shootsCatches": &graphql.Field{Type: graphql.String},
+			"headshot":      &graphql.Field{Type: graphql.String},
+			"stats": &graphql.Field{
+				Type:        graphQLJSON,
+				Description: "Season and career stats",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					source := p.Source.(map[string]interface{})
+					sport, err := s.graphQLScout(source["sport"])
+					if err != nil {
+						return nil, err
+					}
+					playerId := fmt.Sprint(source["playerId"])
+					return resolveGraphQLContent(s.directory.PlayerStats(sport, playerId), fmt.Sprintf("%s stats of player %s", sport.Name(), playerId))
+				},
+			},
+		},
+	})
+	gameUpdate := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "GameUpdate",
+		Description: "Message sent to the websocket clients of a game, only the field of its kind is set",
+		Fields: graphql.Fields{
+			"kind":       &graphql.Field{Type: graphql.String, Description: "plays, events or boxscore"},
+			"type":       &graphql.Field{Type: graphql.String},
+			"playByPlay": &graphql.Field{Type: playByPlay, Description: "Plays since the previous update"},
+			"event":      &graphql.Field{Type: event},
+			"boxScore":   &graphql.Field{Type: graphQLJSON},
+		},
+	})
+	scoreTeam := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ScoreTeam",
+		Fields: graphql.Fields{
+			"score": &graphql.Field{Type: graphql.Int},
+		},
+	})
+	scoreUpdate := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ScoreUpdate",
+		Fields: graphql.Fields{
+			"sport":  &graphql.Field{Type: graphql.String},
+			"gameId": &graphql.Field{Type: graphql.ID},
+			"state":  &graphql.Field{Type: graphQLGameState, Resolve: resolveGraphQLState},
+			"period": &graphql.Field{Type: graphql.Int},
+			"clock":  &graphql.Field{Type: graphql.String},
+			"home":   &graphql.Field{Type: scoreTeam},
+			"away":   &graphql.Field{Type: scoreTeam},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"schedule": &graphql.Field{
+				Type: graphql.NewList(scheduledGame),
+				Args: graphql.FieldConfigArgument{
+					"sport": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"date":  &graphql.ArgumentConfig{Type: graphql.String, Description: "yyyy-mm-dd, today if not set"},
+					"team":  &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					sport, err := s.graphQLSport(p.Args["sport"])
+					if err != nil {
+						return nil, err
+					}
+					date, _ := p.Args["date"].(string)
+					result := sport.Schedule(url.Values{"date": nonEmpty(date)})
+					if result == nil {
+						return nil, fmt.Errorf("%s schedule is temporarily unavailable", sport.Name())
+					}
+					games, _ := result["content"].([]map[string]interface{})
+					if team, ok := p.Args["team"].(string); ok && team != "" {
+						games = filterGamesByTeam(sport, games, team)
+					}
+					return withGraphQLSport(sport, normalizeGraphQL(games)), nil
+				},
+			},
+			"game": &graphql.Field{
+				Type:        playByPlay,
+				Description: "Latest play by play of a game, with every play so far if the game is being watched",
+				Args: graphql.FieldConfigArgument{
+					"sport":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"gameId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					return s.resolveGraphQLPlayByPlay(p.Args["sport"], p.Args["gameId"])
+				},
+			},
+			"boxScore": &graphql.Field{
+				Type: graphQLJSON,
+				Args: graphql.FieldConfigArgument{
+					"sport":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"gameId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					return s.resolveGraphQLBoxScore(p.Args["sport"], p.Args["gameId"])
+				},
+			},
+			"teams": &graphql.Field{
+				Type: graphql.NewList(team),
+				Args: graphql.FieldConfigArgument{
+					"sport": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					sport, err := s.graphQLDirectory(p.Args["sport"])
+					if err != nil {
+						return nil, err
+					}
+					teams, err := resolveGraphQLContent(s.directory.Teams(sport), fmt.Sprintf("%s teams", sport.Name()))
+					return withGraphQLSport(sport, teams), err
+				},
+			},
+			"team": &graphql.Field{
+				Type: team,
+				Args: graphql.FieldConfigArgument{
+					"sport":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"teamId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID), Description: "Stable id or the league's id"},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					sport, err := s.graphQLDirectory(p.Args["sport"])
+					if err != nil {
+						return nil, err
+					}
+					teams, err := resolveGraphQLContent(s.directory.Teams(sport), fmt.Sprintf("%s teams", sport.Name()))
+					if err != nil {
+						return nil, err
+					}
+					teamId := sports.ParseTeamId(sport, p.Args["teamId"].(string))
+					teamsList, _ := withGraphQLSport(sport, teams).([]interface{})
+					for _, team := range teamsList {
+						if fmt.Sprint(team.(map[string]interface{})["teamId"]) == teamId {
+							return team, nil
+						}
+					}
+					return nil, fmt.Errorf("%s team %s was not found", sport.Name(), p.Args["teamId"])
+				},
+			},
+			"players": &graphql.Field{
+				Type:        graphql.NewList(rosterPlayer),
+				Description: "Players of every roster whose name contains name",
+				Args: graphql.FieldConfigArgument{
+					"sport": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"name":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					sport, err := s.graphQLDirectory(p.Args["sport"])
+					if err != nil {
+						return nil, err
+					}
+					nameInterface, httpErr := parseName(url.Values{"name": {p.Args["name"].(string)}})
+					if httpErr != nil {
+						return nil, errors.New(httpErr.text)
+					}
+					return resolveGraphQLContent(s.directory.SearchPlayers(sport, nameInterface.(string)), fmt.Sprintf("%s players", sport.Name()))
+				},
+			},
+			"player": &graphql.Field{
+				Type: player,
+				Args: graphql.FieldConfigArgument{
+					"sport":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"playerId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if err := s.chargeGraphQLUpstream(p.Context); err != nil {
+						return nil, err
+					}
+					sport, err := s.graphQLScout(p.Args["sport"])
+					if err != nil {
+						return nil, err
+					}
+					playerId := p.Args["playerId"].(string)
+					profile, err := resolveGraphQLContent(s.directory.Player(sport, playerId), fmt.Sprintf("%s player %s", sport.Name(), playerId))
+					return withGraphQLSport(sport, profile), err
+				},
+			},
+		},
+	})
+
+	subscription := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"game": &graphql.Field{
+				Type:        gameUpdate,
+				Description: "Updates of a watched game",
+				Args: graphql.FieldConfigArgument{
+					"sport":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"gameId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					sport, err := s.graphQLSport(p.Args["sport"])
+					if err != nil {
+						return nil, err
+					}
+					gameId := p.Args["gameId"].(string)
+					if s.stream.GetGameChannel(sport, gameId) == nil {
+						return nil, fmt.Errorf("Game %s is not being watched", gameId)
+					}
+					return s.subscribeGraphQL(p.Context, []string{broker.Topic(sport.Name(), gameId, "*")}), nil
+				},
+				Resolve: resolveGraphQLGameUpdate,
+			},
+			"scores": &graphql.Field{
+				Type:        scoreUpdate,
+				Description: "Score, clock and state updates of every watched game of the sports",
+				Args: graphql.FieldConfigArgument{
+					"sports": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.String), Description: "Every sport if not set"},
+				},
+				Subscribe: func(p graphql.ResolveParams) (interface{}, error) {
+					names := make([]string, 0)
+					if sportsArg, ok := p.Args["sports"].([]interface{}); ok {
+						for _, sportArg := range sportsArg {
+							names = append(names, fmt.Sprint(sportArg))
+						}
+					}
+					sportsInterface, httpErr := s.parseSports(url.Values{"sports": nonEmpty(strings.Join(names, ","))})
+					if httpErr != nil {
+						return nil, errors.New(httpErr.text)
+					}
+					patterns := make([]string, 0)
+					for _, sport := range sportsInterface.([]sports.Sport) {
+						patterns = append(patterns, broker.Topic(sport.Name(), "*", watch.ScoreMessage))
+					}
+					return s.subscribeGraphQL(p.Context, patterns), nil
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					update, _ := p.Source.(graphQLUpdate)
+					return update.contents, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:        query,
+		Subscription: subscription,
+	})
+}
+
+func (s *server) graphQLSport(sportArg interface{}) (sports.Sport, error) {
+	name := fmt.Sprint(sportArg)
+	sportId := s.sports.ParseSportString(name)
+	if sportId == -1 {
+		return nil, fmt.Errorf("Invalid sport %s", name)
+	}
+	return s.sports.ParseSportId(sportId), nil
+}
+
+func (s *server) graphQLDirectory(sportArg interface{}) (sports.Sport, error) {
+	sport, err := s.graphQLSport(sportArg)
+	if err == nil && !sports.SupportsDirectory(sport) {
+		err = fmt.Errorf("%s does not provide teams", sport.Name())
+	}
+	return sport, err
+}
+
+func (s *server) graphQLScout(sportArg interface{}) (sports.Sport, error) {
+	sport, err := s.graphQLSport(sportArg)
+	if err == nil && !sports.SupportsScout(sport) {
+		err = fmt.Errorf("%s does not provide players", sport.Name())
+	}
+	return sport, err
+}
+
+func (s *server) resolveGraphQLPlayByPlay(sportArg interface{}, gameIdArg interface{}) (interface{}, error) {
+	sport, err := s.graphQLSport(sportArg)
+	if err != nil {
+		return nil, err
+	}
+	gameId := fmt.Sprint(gameIdArg)
+	result := s.stream.GetLatestPlayByPlay(sport, gameId)
+	if result == nil { // Game is not being watched yet
+		result = sport.PlayByPlay(url.Values{"gameId": {gameId}})
+	}
+	if result == nil {
+		return nil, fmt.Errorf("%s play by play of game %s is temporarily unavailable", sport.Name(), gameId)
+	}
+	return normalizeGraphQL(result), nil
+}
+
+func (s *server) resolveGraphQLBoxScore(sportArg interface{}, gameIdArg interface{}) (interface{}, error) {
+	sport, err := s.graphQLSport(sportArg)
+	if err != nil {
+		return nil, err
+	}
+	if !sports.SupportsBoxScore(sport) {
+		return nil, fmt.Errorf("%s does not provide box scores", sport.Name())
+	}
+	gameId := fmt.Sprint(gameIdArg)
+	result := sport.(sports.BoxScorer).BoxScore(url.Values{"gameId": {gameId}})
+	if err := checkUpstreamResult(result, fmt.Sprintf("%s box score of game %s", sport.Name(), gameId)); err != nil {
+		return nil, errors.New(err.text)
+	}
+	return normalizeGraphQL(map[string]interface{}{"home": result["home"], "away": result["away"]}), nil
+}
+
+// resolveGraphQLContent returns the normalized content of an upstream result, or the error checkUpstreamResult reports
+func resolveGraphQLContent(result map[string]interface{}, what string) (interface{}, error) {
+	if err := checkUpstreamResult(result, what); err != nil {
+		return nil, errors.New(err.text)
+	}
+	return normalizeGraphQL(result["content"]), nil
+}
+
+// resolveGraphQLState resolves the state field of a normalized model to a sports.ScheduleState
+func resolveGraphQLState(p graphql.ResolveParams) (interface{}, error) {
+	source, _ := p.Source.(map[string]interface{})
+	state, ok := source["state"].(int64)
+	if !ok {
+		return nil, nil
+	}
+	return sports.ScheduleState(state), nil
+}
+
+// withGraphQLSport sets the sport of normalized models (a model or a list of models),
+// for the resolvers of their fields that request more data
+func withGraphQLSport(sport sports.Sport, models interface{}) interface{} {
+	switch models := models.(type) {
+	case map[string]interface{}:
+		models["sport"] = sport.Name()
+	case []interface{}:
+		for _, model := range models {
+			withGraphQLSport(sport, model)
+		}
+	}
+	return models
+}
+
+// normalizeGraphQL converts a model into its JSON representation, with integers as int64 instead of float64 so that
+// ids are serialized as is. The sports use their own types (sports.ScheduleState, map[string]float64, ...) that the
+// default resolvers and scalars do not support.
+func normalizeGraphQL(model interface{}) interface{} {
+	b, err := json.Marshal(model)
+	if err != nil {
+		log.Errorf("Error normalizing GraphQL model: %s", err)
+		return nil
+	}
+	normalized, _ := decodeGraphQL(b)
+	return normalized
+}
+
+func decodeGraphQL(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return convertGraphQLNumbers(value), nil
+}
+
+func convertGraphQLNumbers(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			value[key] = convertGraphQLNumbers(nested)
+		}
+	case []interface{}:
+		for i, nested := range value {
+			value[i] = convertGraphQLNumbers(nested)
+		}
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return integer
+		}
+		float, _ := value.Float64()
+		return float
+	}
+	return value
+}
+
+// graphQLUpdate is a message the watcher sent, received from s.updates
+type graphQLUpdate struct {
+	kind        string // watch message kind
+	messageType string
+	contents    interface{} // Normalized
+}
+
+func resolveGraphQLGameUpdate(p graphql.ResolveParams) (interface{}, error) {
+	update, _ := p.Source.(graphQLUpdate)
+	result := map[string]interface{}{
+		"kind": update.kind,
+		"type": update.messageType,
+	}
+	switch update.kind {
+	case watch.PlayByPlayMessage:
+		result["playByPlay"] = update.contents
+	case watch.EventMessage:
+		result["event"] = update.contents
+	case watch.BoxScoreMessage:
+		contents, _ := update.contents.(map[string]interface{})
+		result["boxScore"] = map[string]interface{}{"home": contents["home"], "away": contents["away"]}
+	}
+	return result, nil
+}
+
+// subscribeGraphQL returns a channel of the updates published to s.updates on topics matching patterns,
+// until ctx is done
+func (s *server) subscribeGraphQL(ctx context.Context, patterns []string) chan interface{} {
+	updates := make(chan interface{})
+	for _, pattern := range patterns {
+		publications, cancel := s.updates.Subscribe(pattern)
+		go forwardGraphQLUpdates(ctx, publications, cancel, updates)
+	}
+	return updates
+}
+
+// Goroutine function
+func forwardGraphQLUpdates(ctx context.Context, publications <-chan broker.Publication, cancel func(), updates chan<- interface{}) {
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case publication, ok := <-publications:
+			if !ok {
+				return
+			}
+			message := struct {
+				Type     string
+				Contents json.RawMessage
+			}{}
+			if err := json.Unmarshal(publication.Data, &message); err != nil {
+				log.Errorf("Error decoding %s for GraphQL: %s", publication.Topic, err)
+				continue
+			}
+			contents, _ := decodeGraphQL(message.Contents)
+			update := graphQLUpdate{
+				kind:        publication.Topic[strings.LastIndex(publication.Topic, ".")+1:],
+				messageType: message.Type,
+				contents:    contents,
+			}
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
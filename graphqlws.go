@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/henrymxu/gosports/auth"
+	"github.com/ngaut/log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// GraphQL over websocket protocol, https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const graphQLWebsocketProtocol = "graphql-transport-ws"
+const graphQLInitTimeout = 10 * time.Second // Clients must send connection_init within this duration
+const graphQLCloseTimeout = 1 * time.Second
+
+// Message types and close codes of the protocol
+const (
+	graphQLConnectionInit = "connection_init"
+	graphQLConnectionAck  = "connection_ack"
+	graphQLPing           = "ping"
+	graphQLPong           = "pong"
+	graphQLSubscribe      = "subscribe"
+	graphQLNext           = "next"
+	graphQLError          = "error"
+	graphQLComplete       = "complete"
+
+	graphQLInvalidMessage         = 4400
+	graphQLUnauthorized           = 4401
+	graphQLInitialisationTimeout  = 4408
+	graphQLSubscriberExists       = 4409
+	graphQLTooManyInitialisations = 4429
+)
+
+type graphQLWebsocketMessage struct {
+	Id      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphQLSession is a websocket connection of handleGraphQLSubscriptions, operations run concurrently by id
+type graphQLSession struct {
+	socket          *websocket.Conn
+	schema          graphql.Schema
+	apiKey          *auth.APIKey // Charged for the upstream fields of queries, nil for tokens
+	acknowledged    bool
+	writeMutex      sync.Mutex // Connections support only one concurrent writer
+	operations      map[string]context.CancelFunc
+	operationsMutex sync.Mutex
+}
+
+// handleGraphQLSubscriptions serves GraphQL operations, including subscriptions, over websockets with the
+// graphql-transport-ws protocol. Like the other websockets it requires a token or an api key with the live:stream scope.
+func (s *server) handleGraphQLSubscriptions(schema graphql.Schema) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		CheckOrigin:  s.origin.Allowed,
+		Subprotocols: []string{graphQLWebsocketProtocol},
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r = s.authenticateToken(w, r, auth.LiveStream); r == nil {
+			return
+		}
+		apiKey := requestAPIKey(r)
+		if apiKey != nil && !s.quotas.AcquireSocket(apiKey) {
+			logHttpError(w, &httpError{
+				http.StatusTooManyRequests,
+				"Concurrent websocket limit reached",
+			})
+			return
+		}
+		if apiKey != nil {
+			defer s.quotas.ReleaseSocket(apiKey)
+		}
+		socket, err := upgrader.Upgrade(w, r, nil) // Upgrade writes the error response
+		if err != nil {
+			log.Errorf("Error upgrading GraphQL websocket: %s", err)
+			return
+		}
+		session := &graphQLSession{
+			socket:     socket,
+			schema:     schema,
+			apiKey:     apiKey,
+			operations: make(map[string]context.CancelFunc),
+		}
+		session.serve()
+	}
+}
+
+// serve reads the messages of the client until the connection is closed, then cancels every operation
+func (g *graphQLSession) serve() {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), apiKeyContextKey, g.apiKey))
+	defer cancel()
+	defer g.socket.Close()
+	_ = g.socket.SetReadDeadline(time.Now().Add(graphQLInitTimeout))
+	for {
+		_, data, err := g.socket.ReadMessage()
+		if err != nil {
+			if !g.acknowledged {
+				g.close(graphQLInitialisationTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+		message := graphQLWebsocketMessage{}
+		if err := json.Unmarshal(data, &message); err != nil {
+			g.close(graphQLInvalidMessage, "Invalid message received")
+			return
+		}
+		switch message.Type {
+		case graphQLConnectionInit:
+			if g.acknowledged {
+				g.close(graphQLTooManyInitialisations, "Too many initialisation requests")
+				return
+			}
+			g.acknowledged = true
+			_ = g.socket.SetReadDeadline(time.Time{})
+			g.write(graphQLWebsocketMessage{Type: graphQLConnectionAck})
+		case graphQLPing:
+			g.write(graphQLWebsocketMessage{Type: graphQLPong})
+		case graphQLPong:
+		case graphQLSubscribe:
+			if !g.acknowledged {
+				g.close(graphQLUnauthorized, "Unauthorized")
+				return
+			}
+			request := graphQLRequest{}
+			if err := json.Unmarshal(message.Payload, &request); err != nil || message.Id == "" {
+				g.close(graphQLInvalidMessage, "Invalid subscribe message")
+				return
+			}
+			g.operationsMutex.Lock()
+			_, exists := g.operations[message.Id]
+			if !exists {
+				operationCtx, operationCancel := context.WithCancel(ctx)
+				g.operations[message.Id] = operationCancel
+				go g.execute(operationCtx, message.Id, request)
+			}
+			g.operationsMutex.Unlock()
+			if exists {
+				g.close(graphQLSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", message.Id))
+				return
+			}
+		case graphQLComplete:
+			g.operationsMutex.Lock()
+			if operationCancel, ok := g.operations[message.Id]; ok {
+				operationCancel()
+				delete(g.operations, message.Id)
+			}
+			g.operationsMutex.Unlock()
+		default:
+			g.close(graphQLInvalidMessage, fmt.Sprintf("Invalid message type %s", message.Type))
+			return
+		}
+	}
+}
+
+// execute sends the results of an operation until it completes or ctx is done (the client completed it)
+// Goroutine function
+func (g *graphQLSession) execute(ctx context.Context, id string, request graphQLRequest) {
+	params := graphql.Params{
+		Schema:         g.schema,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+		Context:        withGraphQLCost(ctx, 0), // Operations are not charged as requests, every upstream field is
+	}
+	var results chan *graphql.Result
+	if graphQLOperation(request) == ast.OperationTypeSubscription {
+		results = graphql.Subscribe(params)
+	} else {
+		results = make(chan *graphql.Result, 1)
+		results <- graphql.Do(params)
+		close(results)
+	}
+	first := true
+	for result := range results { // Drained after ctx is done, so that the subscription can end
+		if ctx.Err() != nil {
+			continue
+		}
+		if first && result.Data == nil && len(result.Errors) > 0 { // The operation was not executed
+			payload, _ := json.Marshal(result.Errors)
+			g.write(graphQLWebsocketMessage{Id: id, Type: graphQLError, Payload: payload})
+			g.finish(id)
+			return
+		}
+		first = false
+		payload, _ := json.Marshal(result)
+		g.write(graphQLWebsocketMessage{Id: id, Type: graphQLNext, Payload: payload})
+	}
+	if ctx.Err() == nil {
+		g.write(graphQLWebsocketMessage{Id: id, Type: graphQLComplete})
+		g.finish(id)
+	}
+}
+
+// finish removes an operation that completed, so that its id can be reused
+func (g *graphQLSession) finish(id string) {
+	g.operationsMutex.Lock()
+	defer g.operationsMutex.Unlock()
+	if operationCancel, ok := g.operations[id]; ok {
+		operationCancel()
+		delete(g.operations, id)
+	}
+}
+
+func (g *graphQLSession) write(message graphQLWebsocketMessage) {
+	g.writeMutex.Lock()
+	defer g.writeMutex.Unlock()
+	if err := g.socket.WriteJSON(message); err != nil {
+		log.Errorf("Error writing GraphQL %s message: %s", message.Type, err)
+	}
+}
+
+func (g *graphQLSession) close(code int, reason string) {
+	g.writeMutex.Lock()
+	defer g.writeMutex.Unlock()
+	_ = g.socket.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(graphQLCloseTimeout))
+}
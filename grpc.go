@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"github.com/henrymxu/gosports/auth"
+	"github.com/henrymxu/gosports/database"
+	"github.com/henrymxu/gosports/proto"
+	"github.com/henrymxu/gosports/sports"
+	"github.com/henrymxu/gosports/websocket"
+	"github.com/ngaut/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const grpcApiKeyMetadata = "x-api-key" // gRPC equivalent of apiKeyHeader
+
+// goSportsService is the GoSports service of proto/gosports.proto
+type goSportsService interface {
+	GetSchedule(ctx context.Context, request *proto.ScheduleRequest) (*proto.Schedule, error)
+	GetPlayByPlay(ctx context.Context, request *proto.GameRequest) (*proto.PlayByPlay, error)
+	WatchGame(request *proto.GameRequest, stream grpc.ServerStream) error
+	WatchScoreboard(request *proto.ScoreboardRequest, stream grpc.ServerStream) error
+}
+
+// grpcServer implements goSportsService with the sports and watcher of server
+type grpcServer struct {
+	*server
+}
+
+var goSportsServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gosports.GoSports",
+	HandlerType: (*goSportsService)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetSchedule",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				request := &proto.ScheduleRequest{}
+				if err := dec(request); err != nil {
+					return nil, err
+				}
+				return srv.(goSportsService).GetSchedule(ctx, request)
+			},
+		},
+		{
+			MethodName: "GetPlayByPlay",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				request := &proto.GameRequest{}
+				if err := dec(request); err != nil {
+					return nil, err
+				}
+				return srv.(goSportsService).GetPlayByPlay(ctx, request)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "WatchGame",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				request := &proto.GameRequest{}
+				if err := stream.RecvMsg(request); err != nil {
+					return err
+				}
+				return srv.(goSportsService).WatchGame(request, stream)
+			},
+			ServerStreams: true,
+		},
+		{
+			StreamName: "WatchScoreboard",
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				request := &proto.ScoreboardRequest{}
+				if err := stream.RecvMsg(request); err != nil {
+					return err
+				}
+				return srv.(goSportsService).WatchScoreboard(request, stream)
+			},
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/gosports.proto",
+}
+
+// serveGRPC serves the GoSports service on address, separately from the router
+// Goroutine function
+func (s *server) serveGRPC(address string, options ...grpc.ServerOption) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Fatalf("Error listening for gRPC on %s: %s", address, err)
+	}
+	grpcServerInstance := grpc.NewServer(append(options, grpc.ForceServerCodec(proto.Codec{}))...)
+	grpcServerInstance.RegisterService(&goSportsServiceDesc, &grpcServer{s})
+	log.Fatal(grpcServerInstance.Serve(listener))
+}
+
+func (g *grpcServer) GetSchedule(ctx context.Context, request *proto.ScheduleRequest) (*proto.Schedule, error) {
+	if _, err := g.authorizeRPC(ctx, auth.ScheduleRead); err != nil {
+		return nil, err
+	}
+	sport, err := g.parseRPCSport(request.Sport)
+	if err != nil {
+		return nil, err
+	}
+	result := sport.Schedule(url.Values{"date": nonEmpty(request.Date)})
+	if result == nil {
+		return nil, status.Errorf(codes.Unavailable, "%s schedule is temporarily unavailable", sport.Name())
+	}
+	games, _ := result["content"].([]map[string]interface{})
+	if request.Team != "" {
+		games = filterGamesByTeam(sport, games, request.Team)
+	}
+	return &proto.Schedule{Games: games}, nil
+}
+
+func (g *grpcServer) GetPlayByPlay(ctx context.Context, request *proto.GameRequest) (*proto.PlayByPlay, error) {
+	if _, err := g.authorizeRPC(ctx, auth.ScheduleRead); err != nil {
+		return nil, err
+	}
+	sport, err := g.parseRPCSport(request.Sport)
+	if err != nil {
+		return nil, err
+	}
+	result, err := g.latestPlayByPlay(sport, request.GameId)
+	if err != nil {
+		return nil, err
+	}
+	return &proto.PlayByPlay{Contents: result}, nil
+}
+
+// WatchGame is the gRPC equivalent of handlePlayByPlay, the stream ends when the client cancels it
+func (g *grpcServer) WatchGame(request *proto.GameRequest, stream grpc.ServerStream) error {
+	release, err := g.acquireRPCStream(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+	sport, err := g.parseRPCSport(request.Sport)
+	if err != nil {
+		return err
+	}
+	modeInterface, httpErr := parseMode(url.Values{"mode": nonEmpty(request.Mode)})
+	if httpErr != nil {
+		return rpcError(httpErr)
+	}
+	mode := modeInterface.(string)
+	gameChannel := g.stream.GetGameChannel(sport, request.GameId)
+	if gameChannel == nil || g.client.GetChannelHistory(gameChannel) == nil {
+		return status.Errorf(codes.NotFound, "Game %s is not being watched", request.GameId)
+	}
+	history := g.client.GetChannelHistory(gameChannel)
+	cursor := history.Cursor() // Before the initial playbyplay, so no update is missed
+	result, err := g.latestPlayByPlay(sport, request.GameId)
+	if err != nil {
+		return err
+	}
+	if err := sendRPCMessage(stream, websocket.Message{Type: "initial playbyplay", Contents: result}, mode); err != nil {
+		return err
+	}
+	return sendRPCHistories(stream, []*websocket.History{history}, []int{cursor}, mode)
+}
+
+// WatchScoreboard is the gRPC equivalent of handleScoreboardClient, the stream ends when the client cancels it
+func (g *grpcServer) WatchScoreboard(request *proto.ScoreboardRequest, stream grpc.ServerStream) error {
+	release, err := g.acquireRPCStream(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+	sportsInterface, httpErr := g.parseSports(url.Values{"sports": nonEmpty(strings.Join(request.Sports, ","))})
+	if httpErr != nil {
+		return rpcError(httpErr)
+	}
+	sportsList := sportsInterface.([]sports.Sport)
+	histories := make([]*websocket.History, len(sportsList))
+	cursors := make([]int, len(sportsList))
+	for i, sport := range sportsList {
+		histories[i] = g.client.GetChannelHistory(g.stream.GetScoreChannel(sport))
+		if histories[i] == nil {
+			return status.Errorf(codes.Unavailable, "%s scores are temporarily unavailable", sport.Name())
+		}
+		cursors[i] = histories[i].Cursor()
+	}
+	result, _ := g.buildScoreboard(sportsList, nil)
+	if err := sendRPCMessage(stream, websocket.Message{Type: "initial scoreboard", Contents: result}, websocket.FullMode); err != nil {
+		return err
+	}
+	return sendRPCHistories(stream, histories, cursors, websocket.FullMode)
+}
+
+// latestPlayByPlay returns the playbyplay of a game with every play so far if it is being watched
+func (g *grpcServer) latestPlayByPlay(sport sports.Sport, gameId string) (map[string]interface{}, error) {
+	if gameId == "" {
+		return nil, status.Error(codes.InvalidArgument, "Missing required game_id")
+	}
+	result := g.stream.GetLatestPlayByPlay(sport, gameId)
+	if result == nil { // Game is not being watched yet
+		result = sport.PlayByPlay(url.Values{"gameId": {gameId}})
+	}
+	if result == nil {
+		return nil, status.Errorf(codes.Unavailable, "%s play by play is temporarily unavailable", sport.Name())
+	}
+	return result, nil
+}
+
+func (g *grpcServer) parseRPCSport(sportString string) (sports.Sport, error) {
+	sportInterface, httpErr := g.parseSport(map[string]string{"sport": sportString})
+	if httpErr != nil {
+		return nil, rpcError(httpErr)
+	}
+	return g.sports.ParseSportId(sportInterface.(int)), nil
+}
+
+// acquireRPCStream authorizes a Watch stream and counts it towards the key's concurrent websockets,
+// the returned function must be called when the stream ends
+func (g *grpcServer) acquireRPCStream(ctx context.Context) (func(), error) {
+	apiKey, err := g.authorizeRPC(ctx, auth.LiveStream)
+	if err != nil {
+		return nil, err
+	}
+	if !g.quotas.AcquireSocket(apiKey) {
+		return nil, status.Error(codes.ResourceExhausted, "Concurrent stream limit reached")
+	}
+	return func() {
+		g.quotas.ReleaseSocket(apiKey)
+	}, nil
+}
+
+// authorizeRPC is the gRPC equivalent of authorize, the api key is sent in the x-api-key metadata
+func (g *grpcServer) authorizeRPC(ctx context.Context, scope auth.Scope) (*auth.APIKey, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(grpcApiKeyMetadata)
+	if len(keys) == 0 || keys[0] == "" {
+		return nil, status.Error(codes.Unauthenticated, "Missing api key")
+	}
+	apiKey, err := g.keys.GetById(auth.HashKey(keys[0]))
+	if err == database.ErrNotFound {
+		return nil, status.Error(codes.Unauthenticated, "Invalid api key")
+	} else if err != nil {
+		log.Errorf("Error retrieving api key: %s", err)
+		return nil, status.Error(codes.Internal, "Could not verify api key")
+	}
+	if !apiKey.HasScope(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "Api key is missing the %s scope", scope)
+	}
+	if ok, delay := g.quotas.AllowRequest(apiKey); !ok {
+		return nil, status.Errorf(codes.ResourceExhausted, "Request rate limit reached, retry in %s", delay)
+	}
+	return apiKey, nil
+}
+
+// sendRPCHistories sends the messages of histories past cursors to stream until the stream's context is done
+func sendRPCHistories(stream grpc.ServerStream, histories []*websocket.History, cursors []int, mode string) error {
+	ctx := stream.Context()
+	for {
+		messages, nextCursors := websocket.WaitForHistories(ctx, histories, cursors)
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		for i := range histories {
+			for _, message := range messages[i] {
+				if err := sendRPCMessage(stream, message, mode); err != nil {
+					return err
+				}
+			}
+			cursors[i] = nextCursors[i]
+		}
+	}
+}
+
+// sendRPCMessage sends message with the typed model of its contents (PlayByPlay, Event, ScoreUpdate...)
+func sendRPCMessage(stream grpc.ServerStream, message websocket.Message, mode string) error {
+	message = message.ForMode(mode)
+	return stream.SendMsg(&proto.Message{Type: message.Type, Id: int64(message.Id), Contents: message.Contents})
+}
+
+// rpcError converts the errors of the query parsers into gRPC errors
+func rpcError(err *httpError) error {
+	code := codes.Internal
+	switch err.code {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+	return status.Error(code, err.text)
+}
+
+// nonEmpty returns a query value of value, or nil if it is empty so the query is treated as missing
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
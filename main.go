@@ -1,45 +1,142 @@
 package main
 
 import (
+	"crypto/rand"
+	"flag"
 	"github.com/gorilla/mux"
+	"github.com/henrymxu/gosports/auth"
+	"github.com/henrymxu/gosports/broker"
+	"github.com/henrymxu/gosports/config"
 	"github.com/henrymxu/gosports/database"
+	"github.com/henrymxu/gosports/directory"
 	"github.com/henrymxu/gosports/sports"
 	"github.com/henrymxu/gosports/watch"
+	"github.com/henrymxu/gosports/webhooks"
 	"github.com/henrymxu/gosports/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"log"
 	"net/http"
-	"time"
+	"os"
+	"strings"
 )
 
-const serverAddress = "localhost:8080"
-const databaseAddress = "mongodb://localhost:27017"
-
 func main() {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	printConfig := flags.Bool("print-config", false, "Print the configuration (with secrets redacted) and exit")
+	configuration, err := config.Load(flags, os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *printConfig {
+		if err := configuration.Write(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := configuration.ConfigureLogging(); err != nil {
+		log.Fatalf("Error opening log file: %s", err)
+	}
+
 	databaseClient := database.MongoClient{}
-	databaseClient.Initialize(databaseAddress)
-	databaseServer := database.CreateDatabaseServer(&databaseClient)
+	databaseClient.Initialize(configuration.Storage.URI)
+	databaseServer := database.CreateDatabaseServer(&databaseClient, configuration.Storage.TickRate)
+
+	originPolicy := &websocket.OriginPolicy{
+		Origins:          configuration.Origins.Allowed,
+		AllowEmptyOrigin: configuration.Origins.AllowEmpty,
+		AllowSameHost:    true,
+	}
+	websocketServer := websocket.CreateWebsocketServer(originPolicy)
 
-	websocketServer := websocket.CreateWebsocketServer()
+	sportsInstance := sports.InitializeSports(configuration.Sports.Enabled, configuration.UpstreamLimits())
+	streamServer := watch.CreateWatchServer(websocketServer, databaseServer, sportsInstance, watch.Cadence{
+		ScheduleCheck:    configuration.Watch.ScheduleCheck,
+		GameLiveCheck:    configuration.Watch.GameLiveCheck,
+		StandingsRefresh: configuration.Watch.StandingsRefresh,
+	})
+	webhooksInstance := webhooks.CreateWebhooks(
+		databaseServer.GetServerDatabase().Collection(webhooks.SubscriptionsCollectionName),
+		databaseServer.GetServerDatabase().Collection(webhooks.DeadLettersCollectionName),
+		&http.Client{Timeout: configuration.Webhooks.Timeout},
+	)
+	streamServer.AddListener(webhooksInstance)
+	updates := broker.CreateMemoryPublisher()
+	streamServer.AddListener(broker.CreateBridge(updates, websocket.DefaultEncoding(), websocket.FullMode))
+	if configuration.Broker.NATSURL != "" {
+		publisher, err := broker.ConnectNATS(configuration.Broker.NATSURL, "gosports")
+		if err != nil {
+			log.Fatalf("Error connecting to NATS server: %s", err)
+		}
+		encoding := websocket.GetEncoding(configuration.Broker.Encoding)
+		streamServer.AddListener(broker.CreateBridge(publisher, encoding, configuration.Broker.Mode))
+	}
 
-	sportsInstance := sports.InitializeSports()
-	streamServer := watch.CreateWatchServer(websocketServer, sportsInstance)
+	directoryInstance := directory.CreateDirectory(databaseServer)
+	go directoryInstance.RefreshRosters(*sportsInstance)
 
 	router := mux.NewRouter()
 	server := server{
-		stream: streamServer,
-		client: websocketServer,
-		db:     databaseServer,
-		sports: sportsInstance,
-		router: router,
+		stream:    streamServer,
+		client:    websocketServer,
+		db:        databaseServer,
+		sports:    sportsInstance,
+		router:    router,
+		origin:    originPolicy,
+		keys:      auth.CreateKeys(databaseServer.GetServerDatabase().Collection(auth.KeysCollectionName), configuration.Auth.AdminKey),
+		quotas:    auth.CreateQuotas(),
+		signer:    createSigner(configuration.Auth.TokenKeys),
+		directory: directoryInstance,
+		webhooks:  webhooksInstance,
+		updates:   updates,
 	}
 
 	server.routes()
 
+	tls := configuration.Server.TLS
+	var grpcOptions []grpc.ServerOption
+	if tls.CertFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(tls.CertFile, tls.KeyFile)
+		if err != nil {
+			log.Fatalf("Error loading TLS certificate: %s", err)
+		}
+		grpcOptions = append(grpcOptions, grpc.Creds(creds))
+	}
+	go server.serveGRPC(configuration.Server.GRPCAddress, grpcOptions...)
+
 	srv := &http.Server{
 		Handler:      server.router,
-		Addr:         serverAddress,
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
+		Addr:         configuration.Server.Address,
+		WriteTimeout: configuration.Server.WriteTimeout,
+		ReadTimeout:  configuration.Server.ReadTimeout,
+	}
+	if tls.CertFile != "" {
+		log.Fatal(srv.ListenAndServeTLS(tls.CertFile, tls.KeyFile))
 	}
 	log.Fatal(srv.ListenAndServe())
 }
+
+// createSigner creates a token signer from keys with format `kid:secret,kid:secret`.
+// If no keys are provided a random key is generated, so tokens do not outlive the process.
+func createSigner(keys string) *auth.Signer {
+	secrets := make(map[string][]byte)
+	currentKid := ""
+	for _, key := range strings.Split(keys, ",") {
+		parts := strings.SplitN(key, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			continue
+		}
+		if currentKid == "" {
+			currentKid = parts[0]
+		}
+		secrets[parts[0]] = []byte(parts[1])
+	}
+	if currentKid == "" {
+		log.Print("auth.tokenKeys is not set, generating a token signing key")
+		secret := make([]byte, 32)
+		_, _ = rand.Read(secret)
+		currentKid = "generated"
+		secrets[currentKid] = secret
+	}
+	return auth.CreateSigner(currentKid, secrets)
+}
@@ -0,0 +1,531 @@
+package proto
+
+import (
+	"encoding/json"
+	"fmt"
+	"google.golang.org/protobuf/encoding/protowire"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Messages of gosports.proto for the gRPC service. Models are maps built by the sport adapters, so they are
+// encoded field by field instead of through generated code, keep the field numbers in sync with gosports.proto.
+
+// Marshaler is a response of the GoSports service
+type Marshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// Unmarshaler is a request of the GoSports service
+type Unmarshaler interface {
+	Unmarshal(data []byte) error
+}
+
+// Codec is the gRPC codec of the GoSports service, it only supports Marshaler responses and Unmarshaler requests
+type Codec struct{}
+
+func (Codec) Name() string {
+	return "proto"
+}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	marshaler, ok := v.(Marshaler)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a GoSports response", v)
+	}
+	return marshaler.Marshal()
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	unmarshaler, ok := v.(Unmarshaler)
+	if !ok {
+		return fmt.Errorf("%T is not a GoSports request", v)
+	}
+	return unmarshaler.Unmarshal(data)
+}
+
+// Schedule is a schedule's games, as returned by sports.Sport.Schedule
+type Schedule struct {
+	Games []map[string]interface{}
+}
+
+func (s *Schedule) Marshal() ([]byte, error) {
+	var b []byte
+	for _, game := range s.Games {
+		normalized, err := normalize(game)
+		if err != nil {
+			return nil, err
+		}
+		b = appendMessage(b, 1, marshalScheduledGame(normalized))
+	}
+	return b, nil
+}
+
+// PlayByPlay is a play by play, as returned by sports.Sport.PlayByPlay
+type PlayByPlay struct {
+	Contents map[string]interface{}
+}
+
+func (p *PlayByPlay) Marshal() ([]byte, error) {
+	normalized, err := normalize(p.Contents)
+	if err != nil {
+		return nil, err
+	}
+	return marshalPlayByPlay(normalized), nil
+}
+
+// Message is a websocket message, its contents are sent as the typed model of its type, see MarshalMessage
+type Message struct {
+	Type     string
+	Id       int64
+	Contents map[string]interface{}
+}
+
+func (m *Message) Marshal() ([]byte, error) {
+	return MarshalMessage(m.Type, m.Id, m.Contents)
+}
+
+// MarshalMessage encodes a websocket message as a Message, its contents are encoded as the model of messageType
+func MarshalMessage(messageType string, id int64, contents map[string]interface{}) ([]byte, error) {
+	normalized, err := normalize(contents)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	b = appendString(b, 1, messageType)
+	b = appendInt(b, 2, id)
+	switch {
+	case strings.HasPrefix(messageType, "initial playbyplay"), strings.HasPrefix(messageType, "playbyplay update"):
+		if notice, ok := normalized["contents"].(string); ok { // Intermission
+			b = appendMessage(b, 10, appendString(nil, 1, notice))
+		} else {
+			b = appendMessage(b, 4, marshalPlayByPlay(normalized))
+		}
+	case strings.HasPrefix(messageType, "playbyplay patch"):
+		b = appendMessage(b, 5, marshalPlayByPlayPatch(normalized))
+	case strings.HasPrefix(messageType, "scoreboard update"):
+		b = appendMessage(b, 7, marshalScoreUpdate(normalized))
+	case strings.HasPrefix(messageType, "initial scoreboard"):
+		b = appendMessage(b, 8, marshalScoreboard(normalized))
+	case strings.HasPrefix(messageType, "boxscore update"):
+		b = appendMessage(b, 9, marshalBoxScore(normalized))
+	case normalized["type"] == messageType: // Events are sent with their sports.EventType
+		b = appendMessage(b, 6, marshalEvent(normalized))
+	default:
+		encoded, err := json.Marshal(normalized)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, 11, protowire.BytesType)
+		b = protowire.AppendBytes(b, encoded)
+	}
+	return b, nil
+}
+
+type ScheduleRequest struct {
+	Sport string
+	Date  string
+	Team  string
+}
+
+func (r *ScheduleRequest) Unmarshal(data []byte) error {
+	return unmarshalFields(data, func(number protowire.Number, value []byte) {
+		switch number {
+		case 1:
+			r.Sport = string(value)
+		case 2:
+			r.Date = string(value)
+		case 3:
+			r.Team = string(value)
+		}
+	})
+}
+
+type GameRequest struct {
+	Sport  string
+	GameId string
+	Mode   string
+}
+
+func (r *GameRequest) Unmarshal(data []byte) error {
+	return unmarshalFields(data, func(number protowire.Number, value []byte) {
+		switch number {
+		case 1:
+			r.Sport = string(value)
+		case 2:
+			r.GameId = string(value)
+		case 3:
+			r.Mode = string(value)
+		}
+	})
+}
+
+type ScoreboardRequest struct {
+	Sports []string
+}
+
+func (r *ScoreboardRequest) Unmarshal(data []byte) error {
+	return unmarshalFields(data, func(number protowire.Number, value []byte) {
+		if number == 1 {
+			r.Sports = append(r.Sports, string(value))
+		}
+	})
+}
+
+// unmarshalFields calls field with the value of every length delimited field of data, other fields are skipped
+func unmarshalFields(data []byte, field func(number protowire.Number, value []byte)) error {
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		if wireType == protowire.BytesType {
+			value, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return protowire.ParseError(m)
+			}
+			field(number, value)
+			n = m
+		} else {
+			n = protowire.ConsumeFieldValue(number, wireType, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func marshalPlayByPlay(playbyplay map[string]interface{}) []byte {
+	var b []byte
+	if game, ok := playbyplay["game"].(map[string]interface{}); ok {
+		b = appendMessage(b, 1, marshalGame(game))
+	}
+	if players, ok := playbyplay["players"].(map[string]interface{}); ok {
+		b = appendMessage(b, 2, marshalPlayers(players))
+	}
+	for _, play := range objects(playbyplay["plays"]) {
+		b = appendMessage(b, 3, marshalPlay(play))
+	}
+	for _, event := range objects(playbyplay["events"]) {
+		b = appendMessage(b, 4, marshalEvent(event))
+	}
+	if metadata, ok := playbyplay["metadata"].(map[string]interface{}); ok {
+		b = appendMessage(b, 5, marshalMetadata(metadata))
+	}
+	return b
+}
+
+func marshalPlayByPlayPatch(patch map[string]interface{}) []byte {
+	var b []byte
+	if encoded, err := json.Marshal(patch["patch"]); err == nil && patch["patch"] != nil {
+		b = appendString(b, 1, string(encoded))
+	}
+	for _, play := range objects(patch["plays"]) {
+		b = appendMessage(b, 2, marshalPlay(play))
+	}
+	return b
+}
+
+func marshalMetadata(metadata map[string]interface{}) []byte {
+	var b []byte
+	b = appendInt(b, 1, integer(metadata["state"]))
+	b = appendString(b, 2, text(metadata["lastCheck"]))
+	return b
+}
+
+func marshalScoreUpdate(update map[string]interface{}) []byte {
+	var b []byte
+	b = appendString(b, 1, text(update["sport"]))
+	b = appendString(b, 2, text(update["gameId"]))
+	b = appendInt(b, 3, integer(update["state"]))
+	b = appendInt(b, 4, integer(update["period"]))
+	b = appendString(b, 5, text(update["clock"]))
+	b = appendMessage(b, 6, marshalScore(update))
+	return b
+}
+
+// marshalScore encodes the scores of the home and away teams of model as a Score
+func marshalScore(model map[string]interface{}) []byte {
+	var b []byte
+	for i, side := range []string{"home", "away"} {
+		if team, ok := model[side].(map[string]interface{}); ok {
+			b = appendInt(b, protowire.Number(1+i), integer(team["score"]))
+		}
+	}
+	return b
+}
+
+func marshalScoreboard(scoreboard map[string]interface{}) []byte {
+	var b []byte
+	for _, game := range objects(scoreboard["content"]) {
+		var g []byte
+		g = appendString(g, 1, text(game["sport"]))
+		g = appendString(g, 2, text(game["gameId"]))
+		g = appendString(g, 3, text(game["startTime"]))
+		g = appendInt(g, 4, integer(game["state"]))
+		g = appendString(g, 5, text(game["status"]))
+		g = appendInt(g, 6, integer(game["period"]))
+		g = appendString(g, 7, text(game["clock"]))
+		g = appendString(g, 8, text(game["venue"]))
+		for i, side := range []string{"home", "away"} {
+			if team, ok := game[side].(map[string]interface{}); ok {
+				g = appendMessage(g, protowire.Number(9+i), marshalScheduledTeam(team))
+			}
+		}
+		b = appendMessage(b, 1, g)
+	}
+	unavailable, _ := scoreboard["unavailable"].([]interface{})
+	for _, sport := range unavailable {
+		b = appendString(b, 2, text(sport))
+	}
+	return b
+}
+
+func marshalBoxScore(boxScore map[string]interface{}) []byte {
+	var b []byte
+	for i, side := range []string{"home", "away"} {
+		team, ok := boxScore[side].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var t []byte
+		t = appendString(t, 1, text(team["teamId"]))
+		t = appendString(t, 2, text(team["name"]))
+		t = appendString(t, 3, text(team["abbr"]))
+		t = appendStats(t, 4, team["stats"])
+		for _, player := range objects(team["players"]) {
+			var p []byte
+			p = appendString(p, 1, text(player["id"]))
+			p = appendString(p, 2, text(player["name"]))
+			p = appendString(p, 3, text(player["number"]))
+			p = appendString(p, 4, text(player["position"]))
+			p = appendStats(p, 5, player["stats"])
+			t = appendMessage(t, 5, p)
+		}
+		b = appendMessage(b, protowire.Number(1+i), t)
+	}
+	if metadata, ok := boxScore["metadata"].(map[string]interface{}); ok {
+		b = appendMessage(b, 3, marshalMetadata(metadata))
+	}
+	return b
+}
+
+func marshalScheduledTeam(team map[string]interface{}) []byte {
+	var t []byte
+	t = appendString(t, 1, text(team["teamId"]))
+	t = appendString(t, 2, text(team["name"]))
+	t = appendString(t, 3, text(team["abbr"]))
+	t = appendString(t, 4, text(team["record"]))
+	t = appendInt(t, 5, integer(team["score"]))
+	t = appendString(t, 6, text(team["id"]))
+	return t
+}
+
+func marshalScheduledGame(game map[string]interface{}) []byte {
+	var b []byte
+	b = appendString(b, 1, text(game["id"]))
+	b = appendString(b, 2, text(game["date"]))
+	b = appendString(b, 3, text(game["status"]))
+	b = appendInt(b, 4, integer(game["statusCode"]))
+	b = appendInt(b, 5, integer(game["period"]))
+	b = appendString(b, 6, text(game["time"]))
+	for i, side := range []string{"home", "away"} {
+		if team, ok := game[side].(map[string]interface{}); ok {
+			b = appendMessage(b, protowire.Number(7+i), marshalScheduledTeam(team))
+		}
+	}
+	b = appendString(b, 9, text(game["venue"]))
+	return b
+}
+
+func marshalGame(game map[string]interface{}) []byte {
+	var b []byte
+	if status, ok := game["status"].(map[string]interface{}); ok {
+		var s []byte
+		s = appendInt(s, 1, integer(status["period"]))
+		s = appendString(s, 2, text(status["periodTimeRemaining"]))
+		b = appendMessage(b, 1, s)
+	}
+	for i, side := range []string{"home", "away"} {
+		if team, ok := game[side].(map[string]interface{}); ok {
+			var t []byte
+			t = appendString(t, 1, text(team["name"]))
+			t = appendInt(t, 2, integer(team["score"]))
+			t = appendInt(t, 3, integer(team["shots"]))
+			b = appendMessage(b, protowire.Number(2+i), t)
+		}
+	}
+	return b
+}
+
+func marshalPlayers(players map[string]interface{}) []byte {
+	var b []byte
+	for i, side := range []string{"home", "away"} {
+		for _, player := range objects(players[side]) {
+			var p []byte
+			p = appendInt(p, 1, integer(player["id"]))
+			p = appendInt(p, 2, integer(player["onIceDuration"]))
+			p = appendString(p, 3, text(player["name"]))
+			p = appendString(p, 4, text(player["number"]))
+			p = appendString(p, 5, text(player["position"]))
+			b = appendMessage(b, protowire.Number(1+i), p)
+		}
+	}
+	return b
+}
+
+func marshalPlay(play map[string]interface{}) []byte {
+	var b []byte
+	b = appendString(b, 1, text(play["description"]))
+	b = appendString(b, 2, text(play["typeId"]))
+	b = appendString(b, 3, text(play["teamId"]))
+	b = appendString(b, 4, text(play["playerId"]))
+	b = appendString(b, 5, text(play["periodTime"]))
+	b = appendString(b, 6, text(play["dateTime"]))
+	if coordinates, ok := play["coordinates"].(map[string]interface{}); ok {
+		var c []byte
+		c = appendDouble(c, 1, number(coordinates["x"]))
+		c = appendDouble(c, 2, number(coordinates["y"]))
+		b = appendMessage(b, 7, c)
+	}
+	for _, participant := range objects(play["participants"]) {
+		b = appendMessage(b, 8, marshalParticipant(participant))
+	}
+	return b
+}
+
+func marshalEvent(event map[string]interface{}) []byte {
+	var b []byte
+	b = appendString(b, 1, text(event["type"]))
+	b = appendInt(b, 2, integer(event["period"]))
+	b = appendString(b, 3, text(event["periodTime"]))
+	b = appendString(b, 4, text(event["description"]))
+	b = appendString(b, 5, text(event["teamId"]))
+	if score, ok := event["score"].(map[string]interface{}); ok {
+		var s []byte
+		s = appendInt(s, 1, integer(score["home"]))
+		s = appendInt(s, 2, integer(score["away"]))
+		b = appendMessage(b, 6, s)
+	}
+	for _, participant := range objects(event["participants"]) {
+		b = appendMessage(b, 7, marshalParticipant(participant))
+	}
+	return b
+}
+
+func marshalParticipant(participant map[string]interface{}) []byte {
+	var b []byte
+	b = appendString(b, 1, text(participant["role"]))
+	b = appendString(b, 2, text(participant["playerId"]))
+	b = appendString(b, 3, text(participant["name"]))
+	b = appendString(b, 4, text(participant["number"]))
+	b = appendString(b, 5, text(participant["team"]))
+	b = appendString(b, 6, text(participant["teamId"]))
+	return b
+}
+
+// Fields with default values are omitted, as proto3 does
+
+func appendString(b []byte, number protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendString(b, value)
+}
+
+func appendInt(b []byte, number protowire.Number, value int64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, number, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(value))
+}
+
+func appendDouble(b []byte, number protowire.Number, value float64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, number, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(value))
+}
+
+// appendStats encodes the stats of a box score team or player, map<string, string> entries are messages of key and value
+func appendStats(b []byte, number protowire.Number, stats interface{}) []byte {
+	values, _ := stats.(map[string]interface{})
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // Deterministic frames
+	for _, key := range keys {
+		var entry []byte
+		entry = appendString(entry, 1, key)
+		entry = appendString(entry, 2, text(values[key]))
+		b = appendMessage(b, number, entry)
+	}
+	return b
+}
+
+func appendMessage(b []byte, number protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, number, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+// normalize converts a model into its JSON representation, since the sport adapters use different types for the same
+// fields (ids are numbers for the NHL and strings for the NBA)
+func normalize(model map[string]interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(model)
+	if err != nil {
+		return nil, err
+	}
+	normalized := make(map[string]interface{})
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+func objects(value interface{}) []map[string]interface{} {
+	values, _ := value.([]interface{})
+	result := make([]map[string]interface{}, 0, len(values))
+	for _, value := range values {
+		if object, ok := value.(map[string]interface{}); ok {
+			result = append(result, object)
+		}
+	}
+	return result
+}
+
+func text(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	}
+	return ""
+}
+
+func integer(value interface{}) int64 {
+	switch value := value.(type) {
+	case float64:
+		return int64(value)
+	case string:
+		result, _ := strconv.ParseInt(value, 10, 64)
+		return result
+	}
+	return 0
+}
+
+func number(value interface{}) float64 {
+	result, _ := value.(float64)
+	return result
+}
@@ -1,10 +1,90 @@
 package main
 
+import (
+	"github.com/henrymxu/gosports/auth"
+	"github.com/ngaut/log"
+	"net/http"
+)
+
 func (s *server) routes() {
+	s.router.Use(s.cors)
+
+	schema, err := s.graphQLSchema()
+	if err != nil {
+		log.Fatalf("Error building GraphQL schema: %s", err)
+	}
+
 	s.router.HandleFunc("/about", s.handleAbout())
-	s.router.HandleFunc("/schedule/{sport}", s.handleSchedule())
+	s.router.HandleFunc("/schedule/{sport}", s.authenticate(s.handleSchedule(), auth.ScheduleRead))
+
+	s.router.HandleFunc("/scoreboard", s.authenticate(s.checkValidQueries(s.handleScoreboard(),
+		[]ValidateParameter{},
+		[]ValidateQuery{s.parseSports}), auth.ScheduleRead))
+	s.router.HandleFunc("/scoreboard/client", s.checkValidQueries(s.websocketUpgrade(s.handleScoreboardClient(), auth.LiveStream),
+		[]ValidateParameter{},
+		[]ValidateQuery{s.parseSports, parseEncoding}))
+	s.router.HandleFunc("/scoreboard/stream", s.checkValidQueries(s.handleScoreboardStream(),
+		[]ValidateParameter{},
+		[]ValidateQuery{s.parseSports}))
+
+	s.router.HandleFunc("/calendar/{sport}.ics", s.authenticate(s.checkValidQueries(s.handleCalendar(),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{parseCalendarRange}), auth.ScheduleRead))
+
+	s.router.HandleFunc("/feeds/{sport}.atom", s.authenticate(s.checkValidQueries(s.handleFeed(atomFeed),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{}), auth.ScheduleRead))
+	s.router.HandleFunc("/feeds/{sport}.rss", s.authenticate(s.checkValidQueries(s.handleFeed(rssFeed),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{}), auth.ScheduleRead))
+
+	s.router.HandleFunc("/boxscore/{sport}", s.authenticate(s.checkValidQueries(s.handleBoxScore(),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{parseGameId}), auth.ScheduleRead))
+
+	s.router.HandleFunc("/standings/{sport}", s.authenticate(s.checkValidQueries(s.handleStandings(),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{}), auth.ScheduleRead))
+
+	s.router.HandleFunc("/teams/{sport}", s.authenticate(s.checkValidQueries(s.handleTeams(),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{}), auth.ScheduleRead))
+	s.router.HandleFunc("/teams/{sport}/{teamId}", s.authenticate(s.checkValidQueries(s.handleTeam(),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{}), auth.ScheduleRead))
+	s.router.HandleFunc("/teams/{sport}/{teamId}/roster", s.authenticate(s.checkValidQueries(s.handleRoster(),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{}), auth.ScheduleRead))
+
+	s.router.HandleFunc("/players/{sport}", s.authenticate(s.checkValidQueries(s.handleSearchPlayers(),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{parseName}), auth.ScheduleRead))
+	s.router.HandleFunc("/players/{sport}/{playerId}", s.authenticate(s.checkValidQueries(s.handlePlayer(),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{}), auth.ScheduleRead))
+	s.router.HandleFunc("/players/{sport}/{playerId}/stats", s.authenticate(s.checkValidQueries(s.handlePlayerStats(),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{}), auth.ScheduleRead))
+
+	s.router.HandleFunc("/client/{sport}", s.checkValidQueries(s.websocketUpgrade(s.handlePlayByPlay(), auth.LiveStream),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{parseGameId, parseMode, parseEncoding}))
+	s.router.HandleFunc("/poll/{sport}", s.authenticate(s.checkValidQueries(s.handleLongPoll(),
+		[]ValidateParameter{s.parseSport},
+		[]ValidateQuery{parseGameId, parseMode}), auth.LiveStream))
+
+	s.router.HandleFunc("/graphql", s.authenticate(s.handleGraphQL(schema), auth.ScheduleRead)).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+	s.router.HandleFunc("/graphql/ws", s.handleGraphQLSubscriptions(schema))
+
+	s.router.HandleFunc("/token", s.authenticate(s.handleCreateToken(), auth.LiveStream)).Methods(http.MethodPost, http.MethodOptions)
+
+	s.router.HandleFunc("/admin/keys", s.authenticate(s.handleCreateKey(), auth.Admin)).Methods(http.MethodPost, http.MethodOptions)
+	s.router.HandleFunc("/admin/keys/{id}", s.authenticate(s.handleGetKey(), auth.Admin)).Methods(http.MethodGet, http.MethodOptions)
+	s.router.HandleFunc("/admin/keys/{id}", s.authenticate(s.handleDeleteKey(), auth.Admin)).Methods(http.MethodDelete, http.MethodOptions)
 
-	s.router.HandleFunc("/client/{sport}", s.checkValidQueries(s.websocketUpgrade(s.handlePlayByPlay()),
-		[]ValidateParameter{parseSport},
-		[]ValidateQuery{parseGameId}))
+	s.router.HandleFunc("/admin/webhooks", s.authenticate(s.handleListWebhooks(), auth.Admin)).Methods(http.MethodGet, http.MethodOptions)
+	s.router.HandleFunc("/admin/webhooks", s.authenticate(s.handleCreateWebhook(), auth.Admin)).Methods(http.MethodPost, http.MethodOptions)
+	s.router.HandleFunc("/admin/webhooks/deliveries", s.authenticate(s.handleListDeliveries(), auth.Admin)).Methods(http.MethodGet, http.MethodOptions)
+	s.router.HandleFunc("/admin/webhooks/deliveries/{id}/replay", s.authenticate(s.handleReplayDelivery(), auth.Admin)).Methods(http.MethodPost, http.MethodOptions)
+	s.router.HandleFunc("/admin/webhooks/{id}", s.authenticate(s.handleDeleteWebhook(), auth.Admin)).Methods(http.MethodDelete, http.MethodOptions)
 }
@@ -1,24 +1,85 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"github.com/gorilla/feeds"
 	"github.com/gorilla/mux"
+	"github.com/henrymxu/gosports/auth"
+	"github.com/henrymxu/gosports/broker"
+	"github.com/henrymxu/gosports/calendar"
+	"github.com/henrymxu/gosports/config"
 	"github.com/henrymxu/gosports/database"
+	"github.com/henrymxu/gosports/directory"
 	"github.com/henrymxu/gosports/sports"
 	"github.com/henrymxu/gosports/watch"
+	"github.com/henrymxu/gosports/webhooks"
 	"github.com/henrymxu/gosports/websocket"
 	"github.com/ngaut/log"
+	"html"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const defaultLongPollTimeout = 10 * time.Second
+const maxLongPollTimeout = config.LongPollTimeout // config.Validate keeps it below the http.Server WriteTimeout
+
+const serverSentEventsRetry = 1 * time.Second // Delay before EventSource clients reconnect once a stream ends
+
+const corsAllowedMethods = "GET, POST, DELETE, OPTIONS"
+const corsAllowedHeaders = "Content-Type, If-None-Match, " + apiKeyHeader
+const corsExposedHeaders = "ETag, Retry-After"
+const corsMaxAge = "600"
+
+const directoryCacheDuration = 1 * time.Hour     // Max age of teams, rosters and players
+const playerStatsCacheDuration = 5 * time.Minute // Max age of player stats, they change during live games
+const minSearchLength = 2
+
+const calendarCacheDuration = 1 * time.Hour
+const defaultCalendarDays = 7 // Days of a calendar without an end query, starting from start
+const maxCalendarDays = 10    // Schedules are requested one day at a time, so every day must fit in the WriteTimeout at the upstream limit
+
+// Expected length of games, calendar events end after this
+var gameDurations = map[string]time.Duration{
+	"nhl": 2*time.Hour + 30*time.Minute,
+	"nba": 2*time.Hour + 30*time.Minute,
+	"nfl": 3*time.Hour + 30*time.Minute,
+	"mlb": 3 * time.Hour,
+}
+
+const feedCacheDuration = 1 * time.Minute
+
+const (
+	atomFeed = "atom"
+	rssFeed  = "rss"
+)
+
+type calendarRange struct {
+	start time.Time
+	end   time.Time // Inclusive
+}
+
 type server struct {
-	stream *watch.Server
-	client *websocket.Server
-	db     *database.Server
-	sports *sports.Sports
-	router *mux.Router
+	stream    *watch.Server
+	client    *websocket.Server
+	db        *database.Server
+	sports    *sports.Sports
+	router    *mux.Router
+	origin    *websocket.OriginPolicy
+	keys      *auth.Keys
+	quotas    *auth.Quotas
+	signer    *auth.Signer
+	directory *directory.Directory
+	webhooks  *webhooks.Webhooks
+	updates   *broker.MemoryPublisher // Messages of every game, for GraphQL subscriptions
 }
 
 type httpError struct {
@@ -42,18 +103,27 @@ func (s *server) handleSchedule() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		params := mux.Vars(r)
 		query := r.URL.Query()
-		sportInterface, err := parseSport(params)
+		sportInterface, err := s.parseSport(params)
 		if err != nil {
 			logHttpError(w, err)
 			return
 		}
 		sport := s.sports.ParseSportId(sportInterface.(int))
-		result := sport.Schedule(query)
-		games := result["content"].([]map[string]interface{})
+		result := sport.Schedule(url.Values{"date": query["date"]})
+		if err := checkUpstreamResult(result, fmt.Sprintf("%s schedule", sport.Name())); err != nil {
+			logHttpError(w, err)
+			return
+		}
+		games, _ := result["content"].([]map[string]interface{})
+		if team := query.Get("team"); team != "" {
+			games = filterGamesByTeam(sport, games, team)
+		}
+		var body bytes.Buffer
 		for _, game := range games {
 			b, _ := json.MarshalIndent(game, "", "  ")
-			_, _ = fmt.Fprintf(w, "ScheduledGame %s:", string(b))
+			_, _ = fmt.Fprintf(&body, "ScheduledGame %s:", string(b))
 		}
+		writeCacheable(w, r, body.Bytes(), sports.ScheduleCacheDuration(sport, result))
 	}
 }
 
@@ -61,16 +131,22 @@ func (s *server) handlePlayByPlay() WebsocketHandlerFunc {
 	return func(ws *websocket.Client, w http.ResponseWriter, r *http.Request) {
 		params := mux.Vars(r)
 		query := r.URL.Query()
-		sportInterface, err := parseSport(params)
+		sportInterface, err := s.parseSport(params)
 		if err != nil {
 
 		}
 		sport := s.sports.ParseSportId((sportInterface).(int))
 		gameIdInterface, _ := parseGameId(query)
 
+		modeInterface, _ := parseMode(query)
+		ws.Mode = modeInterface.(string)
+
 		pbpChannel := s.stream.GetGameChannel(sport, gameIdInterface.(string))
 		s.client.RegisterClientToWriteChannel(ws, pbpChannel)
-		result := sport.PlayByPlay(query)
+		result := s.stream.GetLatestPlayByPlay(sport, gameIdInterface.(string))
+		if result == nil { // Game is not being watched yet
+			result = sport.PlayByPlay(url.Values{"gameId": {gameIdInterface.(string)}})
+		}
 		message := websocket.Message {
 			Type: "initial playbyplay",
 			Contents: result,
@@ -79,7 +155,594 @@ func (s *server) handlePlayByPlay() WebsocketHandlerFunc {
 	}
 }
 
-func parseSport(params map[string]string) (interface{}, *httpError) {
+// handleLongPoll blocks until a new message is written to any of the requested games' channels,
+// or the timeout elapses, and returns every message since the client supplied cursor.
+// cursor is opaque to clients, it is returned with every response and should be sent back unmodified.
+func (s *server) handleLongPoll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := mux.Vars(r)
+		query := r.URL.Query()
+		sportInterface, _ := s.parseSport(params)
+		sport := s.sports.ParseSportId(sportInterface.(int))
+		gameIdInterface, _ := parseGameId(query)
+		gameIds := strings.Split(gameIdInterface.(string), ",")
+		cursors := parseLongPollCursor(query.Get("cursor"))
+		modeInterface, _ := parseMode(query)
+
+		histories := make([]*websocket.History, len(gameIds))
+		gameCursors := make([]int, len(gameIds))
+		for i, gameId := range gameIds {
+			gameChannel := s.stream.GetGameChannel(sport, gameId)
+			if gameChannel == nil || s.client.GetChannelHistory(gameChannel) == nil {
+				logHttpError(w, &httpError{
+					http.StatusNotFound,
+					fmt.Sprintf("Game %s is not being watched", gameId),
+				})
+				return
+			}
+			histories[i] = s.client.GetChannelHistory(gameChannel)
+			gameCursors[i] = cursors[gameId]
+		}
+
+		timeout := defaultLongPollTimeout
+		if seconds, err := strconv.Atoi(query.Get("timeout")); err == nil && seconds >= 0 {
+			timeout = time.Duration(seconds) * time.Second
+			if timeout > maxLongPollTimeout {
+				timeout = maxLongPollTimeout
+			}
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		messages, nextCursors := websocket.WaitForHistories(ctx, histories, gameCursors)
+
+		games := make(map[string][]websocket.Message)
+		cursorStrings := make([]string, len(gameIds))
+		for i, gameId := range gameIds {
+			for j, message := range messages[i] {
+				messages[i][j] = message.ForMode(modeInterface.(string))
+			}
+			games[gameId] = messages[i]
+			cursorStrings[i] = fmt.Sprintf("%s:%d", gameId, nextCursors[i])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"cursor": strings.Join(cursorStrings, ","),
+			"games":  games,
+		})
+	}
+}
+
+func (s *server) handleScoreboard() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		sportsInterface, _ := s.parseSports(query)
+		sportsList := sportsInterface.([]sports.Sport)
+		result, maxAge := s.buildScoreboard(sportsList, query["date"])
+		if unavailable := result["unavailable"].([]string); len(unavailable) == len(sportsList) {
+			logHttpError(w, &httpError{
+				http.StatusServiceUnavailable,
+				fmt.Sprintf("%s schedules are temporarily unavailable", strings.Join(unavailable, ", ")),
+			})
+			return
+		}
+		body, _ := json.Marshal(result)
+		writeCacheable(w, r, body, maxAge)
+	}
+}
+
+// handleScoreboardClient sends the scoreboard of the requested sports, followed by the score updates of their watched games
+func (s *server) handleScoreboardClient() WebsocketHandlerFunc {
+	return func(ws *websocket.Client, w http.ResponseWriter, r *http.Request) {
+		sportsInterface, _ := s.parseSports(r.URL.Query())
+		for _, sport := range sportsInterface.([]sports.Sport) {
+			s.client.RegisterClientToWriteChannel(ws, s.stream.GetScoreChannel(sport))
+		}
+		result, _ := s.buildScoreboard(sportsInterface.([]sports.Sport), nil)
+		s.client.WriteToClient(ws, websocket.Message{
+			Type:     "initial scoreboard",
+			Contents: result,
+		})
+	}
+}
+
+// handleScoreboardStream is the server-sent events equivalent of handleScoreboardClient.
+// Streams end before the server's write timeout, EventSource clients then reconnect with the id of the last event
+// they received (Last-Event-ID) and resume from it. Streams without an id start with the scoreboard.
+func (s *server) handleScoreboardStream() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r = s.authenticateToken(w, r, auth.LiveStream); r == nil {
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			logHttpError(w, &httpError{
+				http.StatusInternalServerError,
+				"Streaming is not supported",
+			})
+			return
+		}
+		query := r.URL.Query()
+		sportsInterface, _ := s.parseSports(query)
+		sportsList := sportsInterface.([]sports.Sport)
+		lastEventId := r.Header.Get("Last-Event-ID")
+		if lastEventId == "" {
+			lastEventId = query.Get("cursor")
+		}
+		cursors := parseLongPollCursor(lastEventId)
+		histories := make([]*websocket.History, len(sportsList))
+		sportCursors := make([]int, len(sportsList))
+		for i, sport := range sportsList {
+			histories[i] = s.client.GetChannelHistory(s.stream.GetScoreChannel(sport))
+			if histories[i] == nil {
+				logHttpError(w, &httpError{
+					http.StatusServiceUnavailable,
+					fmt.Sprintf("%s scores are temporarily unavailable", sport.Name()),
+				})
+				return
+			}
+			cursor, ok := cursors[sport.Name()]
+			if !ok {
+				cursor = histories[i].Cursor()
+			}
+			sportCursors[i] = cursor
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = fmt.Fprintf(w, "retry: %d\n\n", serverSentEventsRetry.Milliseconds())
+		if lastEventId == "" {
+			result, _ := s.buildScoreboard(sportsList, nil)
+			writeServerSentEvent(w, formatScoreCursor(sportsList, sportCursors), websocket.Message{
+				Type:     "initial scoreboard",
+				Contents: result,
+			})
+		}
+		flusher.Flush()
+
+		ctx, cancel := context.WithTimeout(r.Context(), maxLongPollTimeout)
+		defer cancel()
+		for ctx.Err() == nil {
+			messages, nextCursors := websocket.WaitForHistories(ctx, histories, sportCursors)
+			for i := range sportsList {
+				first := nextCursors[i] - len(messages[i])
+				for j, message := range messages[i] {
+					sportCursors[i] = first + j + 1
+					writeServerSentEvent(w, formatScoreCursor(sportsList, sportCursors), message)
+				}
+				sportCursors[i] = nextCursors[i]
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// buildScoreboard returns the games of sportsList on date (today if empty) in `content`, with the scores of watched
+// games from the watcher, and the sports whose schedules are unavailable in `unavailable`.
+// The max age is the shortest cache duration of the schedules.
+func (s *server) buildScoreboard(sportsList []sports.Sport, date []string) (map[string]interface{}, time.Duration) {
+	games := make([]map[string]interface{}, 0)
+	unavailable := make([]string, 0)
+	maxAge := time.Duration(-1)
+	for _, sport := range sportsList {
+		schedule := sport.Schedule(url.Values{"date": date})
+		if status := sports.UpstreamStatus(schedule); schedule == nil || status < 200 || status >= 300 {
+			unavailable = append(unavailable, sport.Name())
+			maxAge = 0
+			continue
+		}
+		for _, game := range sports.CheckActiveGames(sport, schedule) {
+			scoreboardGame := sports.BuildScoreboardGame(sport, game)
+			if latest := s.stream.GetLatestPlayByPlay(sport, game.Id); latest != nil {
+				sports.ApplyScoreboardUpdate(scoreboardGame, sports.BuildScoreboardUpdate(sport, game.Id, latest))
+			}
+			games = append(games, scoreboardGame)
+		}
+		if duration := sports.ScheduleCacheDuration(sport, schedule); maxAge < 0 || duration < maxAge {
+			maxAge = duration
+		}
+	}
+	sports.SortScoreboard(games)
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	return map[string]interface{}{
+		"content":     games,
+		"unavailable": unavailable,
+	}, maxAge
+}
+
+// formatScoreCursor formats the cursors of the score channels of sportsList with format `sport:n,sport:n`
+func formatScoreCursor(sportsList []sports.Sport, cursors []int) string {
+	cursorStrings := make([]string, len(sportsList))
+	for i, sport := range sportsList {
+		cursorStrings[i] = fmt.Sprintf("%s:%d", sport.Name(), cursors[i])
+	}
+	return strings.Join(cursorStrings, ",")
+}
+
+// writeServerSentEvent writes message (as JSON, like websocket frames) as a server-sent event with id
+func writeServerSentEvent(w io.Writer, id string, message websocket.Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Errorf("Error encoding server-sent event: %s", err)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "id: %s\ndata: %s\n\n", id, data)
+}
+
+// handleCalendar returns the games of a sport (or of a team) between the start and end queries as an iCalendar
+func (s *server) handleCalendar() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sportInterface, _ := s.parseSport(mux.Vars(r))
+		sport := s.sports.ParseSportId(sportInterface.(int))
+		query := r.URL.Query()
+		rangeInterface, _ := parseCalendarRange(query)
+		dates := rangeInterface.(calendarRange)
+		team := query.Get("team")
+		events := make([]calendar.Event, 0)
+		for date := dates.start; !date.After(dates.end); date = date.AddDate(0, 0, 1) {
+			schedule := sport.Schedule(url.Values{"date": {date.Format("2006-01-02")}})
+			// Calendar applications delete the events missing from a subscribed calendar, so a calendar without
+			// every day is not served
+			if err := checkUpstreamResult(schedule, fmt.Sprintf("%s schedule of %s", sport.Name(), date.Format("2006-01-02"))); err != nil {
+				err.code = http.StatusServiceUnavailable
+				logHttpError(w, err)
+				return
+			}
+			if team != "" {
+				games, _ := schedule["content"].([]map[string]interface{})
+				schedule = map[string]interface{}{"content": filterGamesByTeam(sport, games, team)}
+			}
+			for _, game := range sports.CheckActiveGames(sport, schedule) {
+				events = append(events, buildCalendarEvent(sport, game))
+			}
+		}
+		name := strings.ToUpper(sport.Name())
+		if team != "" {
+			name = fmt.Sprintf("%s %s", name, team)
+		}
+		var body bytes.Buffer
+		// Events are stamped with the start of the cache period, so the calendar only changes when its games do
+		_ = calendar.Write(&body, name, events, time.Now().Truncate(calendarCacheDuration))
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		writeCacheable(w, r, body.Bytes(), calendarCacheDuration)
+	}
+}
+
+// buildCalendarEvent converts a scheduled game of sport into a calendar event, with the score once the game is final
+func buildCalendarEvent(sport sports.Sport, game sports.ScheduledGame) calendar.Event {
+	home, _ := game.Details["home"].(map[string]interface{})
+	away, _ := game.Details["away"].(map[string]interface{})
+	event := calendar.Event{
+		UID:         fmt.Sprintf("%s-%s@gosports", sport.Name(), game.Id),
+		Start:       game.StartTime,
+		End:         game.StartTime.Add(gameDurations[sport.Name()]),
+		Summary:     fmt.Sprintf("%s at %s", away["name"], home["name"]),
+		Location:    fmt.Sprint(game.Details["venue"]),
+		Description: fmt.Sprint(game.Details["status"]),
+		Cancelled:   game.ScheduleState == sports.Postponed,
+	}
+	if game.ScheduleState == sports.Complete {
+		event.Description = fmt.Sprintf("Final: %s %v, %s %v", away["name"], away["score"], home["name"], home["score"])
+	}
+	return event
+}
+
+// handleFeed returns the recently completed games of a sport that were watched as an Atom or RSS feed,
+// with the scoring plays of each game in its content if the plays query is true
+func (s *server) handleFeed(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sportInterface, _ := s.parseSport(mux.Vars(r))
+		sport := s.sports.ParseSportId(sportInterface.(int))
+		plays := r.URL.Query().Get("plays") == "true"
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		link := fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.Path)
+		if plays {
+			link += "?plays=true"
+		}
+		feed := &feeds.Feed{
+			Title:       fmt.Sprintf("%s final scores", strings.ToUpper(sport.Name())),
+			Link:        &feeds.Link{Href: link},
+			Description: fmt.Sprintf("Recently completed %s games", strings.ToUpper(sport.Name())),
+		}
+		for _, game := range s.stream.GetCompletedGames(sport) {
+			feed.Add(buildFeedItem(sport, game, plays))
+		}
+		if len(feed.Items) > 0 {
+			feed.Updated = feed.Items[0].Updated
+		} else {
+			feed.Updated = time.Now().Truncate(feedCacheDuration)
+		}
+		var body bytes.Buffer
+		var err error
+		if format == atomFeed {
+			w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+			err = feed.WriteAtom(&body)
+		} else {
+			w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+			err = feed.WriteRss(&body)
+		}
+		if err != nil {
+			log.Errorf("Error encoding %s feed: %s", format, err)
+			logHttpError(w, &httpError{
+				http.StatusInternalServerError,
+				"Could not create feed",
+			})
+			return
+		}
+		writeCacheable(w, r, body.Bytes(), feedCacheDuration)
+	}
+}
+
+// buildFeedItem converts a completed game of sport into a feed item, with its scoring plays (HTML) if plays is true
+func buildFeedItem(sport sports.Sport, game watch.CompletedGame, plays bool) *feeds.Item {
+	names := make(map[string]interface{})
+	scores := make(map[string]interface{})
+	latestGame, _ := game.PlayByPlay["game"].(map[string]interface{})
+	for _, side := range []string{"home", "away"} {
+		team, _ := game.Details[side].(map[string]interface{})
+		names[side] = team["name"]
+		scores[side] = team["score"]
+		if latestTeam, ok := latestGame[side].(map[string]interface{}); ok {
+			scores[side] = latestTeam["score"]
+		}
+	}
+	item := &feeds.Item{
+		Id:          fmt.Sprintf("urn:gosports:%s:%s", sport.Name(), game.Id),
+		Title:       fmt.Sprintf("Final: %s %v, %s %v", names["away"], scores["away"], names["home"], scores["home"]),
+		Description: fmt.Sprintf("%s at %s, %s", names["away"], names["home"], game.Details["venue"]),
+		IsPermaLink: "false",
+		Created:     game.Completed,
+		Updated:     game.Completed,
+	}
+	if plays {
+		var content strings.Builder
+		content.WriteString("<ul>")
+		events, _ := game.PlayByPlay["events"].([]map[string]interface{})
+		for _, event := range events {
+			if fmt.Sprint(event["type"]) != string(sports.ScoreChange) { // A string once loaded from the database
+				continue
+			}
+			score, _ := event["score"].(map[string]interface{})
+			_, _ = fmt.Fprintf(&content, "<li>Period %v %v: %s (%v-%v)</li>", event["period"], event["periodTime"],
+				html.EscapeString(fmt.Sprint(event["description"])), score["away"], score["home"])
+		}
+		content.WriteString("</ul>")
+		item.Content = content.String()
+	}
+	return item
+}
+
+func (s *server) handleBoxScore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sportInterface, _ := s.parseSport(mux.Vars(r))
+		sport := s.sports.ParseSportId(sportInterface.(int))
+		gameIdInterface, _ := parseGameId(r.URL.Query())
+		if !sports.SupportsBoxScore(sport) {
+			logHttpError(w, &httpError{
+				http.StatusNotImplemented,
+				fmt.Sprintf("%s does not provide box scores", sport.Name()),
+			})
+			return
+		}
+		result := sport.(sports.BoxScorer).BoxScore(url.Values{"gameId": {gameIdInterface.(string)}})
+		if err := checkUpstreamResult(result, fmt.Sprintf("%s box score of game %s", sport.Name(), gameIdInterface)); err != nil {
+			logHttpError(w, err)
+			return
+		}
+		body, _ := json.Marshal(result)
+		writeCacheable(w, r, body, sports.BoxScoreCacheDuration(result))
+	}
+}
+
+func (s *server) handleStandings() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sportInterface, _ := s.parseSport(mux.Vars(r))
+		sport := s.sports.ParseSportId(sportInterface.(int))
+		if !sports.SupportsStandings(sport) {
+			logHttpError(w, &httpError{
+				http.StatusNotImplemented,
+				fmt.Sprintf("%s does not provide standings", sport.Name()),
+			})
+			return
+		}
+		result := sport.(sports.Ranker).Standings(nil)
+		if err := checkUpstreamResult(result, fmt.Sprintf("%s standings", sport.Name())); err != nil {
+			logHttpError(w, err)
+			return
+		}
+		body, _ := json.Marshal(result)
+		writeCacheable(w, r, body, sports.StandingsCacheDuration)
+	}
+}
+
+func (s *server) handleTeams() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sport := s.directorySport(w, r)
+		if sport == nil {
+			return
+		}
+		result := s.directory.Teams(sport)
+		if err := checkUpstreamResult(result, fmt.Sprintf("%s teams", sport.Name())); err != nil {
+			logHttpError(w, err)
+			return
+		}
+		body, _ := json.Marshal(result)
+		writeCacheable(w, r, body, directoryCacheDuration)
+	}
+}
+
+func (s *server) handleTeam() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sport := s.directorySport(w, r)
+		if sport == nil {
+			return
+		}
+		team := s.findTeam(w, sport, mux.Vars(r)["teamId"])
+		if team == nil {
+			return
+		}
+		body, _ := json.Marshal(team)
+		writeCacheable(w, r, body, directoryCacheDuration)
+	}
+}
+
+func (s *server) handleRoster() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sport := s.directorySport(w, r)
+		if sport == nil {
+			return
+		}
+		team := s.findTeam(w, sport, mux.Vars(r)["teamId"])
+		if team == nil {
+			return
+		}
+		result := s.directory.Roster(sport, fmt.Sprint(team["teamId"]))
+		if err := checkUpstreamResult(result, fmt.Sprintf("%s roster of team %s", sport.Name(), team["id"])); err != nil {
+			logHttpError(w, err)
+			return
+		}
+		body, _ := json.Marshal(result)
+		writeCacheable(w, r, body, directoryCacheDuration)
+	}
+}
+
+// directorySport returns the sport of the request, or writes an error and returns nil if the sport does not provide teams
+func (s *server) directorySport(w http.ResponseWriter, r *http.Request) sports.Sport {
+	sportInterface, _ := s.parseSport(mux.Vars(r))
+	sport := s.sports.ParseSportId(sportInterface.(int))
+	if !sports.SupportsDirectory(sport) {
+		logHttpError(w, &httpError{
+			http.StatusNotImplemented,
+			fmt.Sprintf("%s does not provide teams", sport.Name()),
+		})
+		return nil
+	}
+	return sport
+}
+
+// findTeam returns the team of sport with teamId (stable or the league's id), or writes an error and returns nil
+func (s *server) findTeam(w http.ResponseWriter, sport sports.Sport, teamId string) map[string]interface{} {
+	result := s.directory.Teams(sport)
+	if err := checkUpstreamResult(result, fmt.Sprintf("%s teams", sport.Name())); err != nil {
+		logHttpError(w, err)
+		return nil
+	}
+	teams, _ := result["content"].([]map[string]interface{})
+	for _, team := range teams {
+		if fmt.Sprint(team["teamId"]) == sports.ParseTeamId(sport, teamId) {
+			return team
+		}
+	}
+	logHttpError(w, &httpError{
+		http.StatusNotFound,
+		fmt.Sprintf("%s team %s was not found", sport.Name(), teamId),
+	})
+	return nil
+}
+
+func (s *server) handlePlayer() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sport := s.scoutSport(w, r)
+		if sport == nil {
+			return
+		}
+		playerId := mux.Vars(r)["playerId"]
+		result := s.directory.Player(sport, playerId)
+		if err := checkUpstreamResult(result, fmt.Sprintf("%s player %s", sport.Name(), playerId)); err != nil {
+			logHttpError(w, err)
+			return
+		}
+		body, _ := json.Marshal(result)
+		writeCacheable(w, r, body, directoryCacheDuration)
+	}
+}
+
+func (s *server) handlePlayerStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sport := s.scoutSport(w, r)
+		if sport == nil {
+			return
+		}
+		playerId := mux.Vars(r)["playerId"]
+		result := s.directory.PlayerStats(sport, playerId)
+		if err := checkUpstreamResult(result, fmt.Sprintf("%s stats of player %s", sport.Name(), playerId)); err != nil {
+			logHttpError(w, err)
+			return
+		}
+		body, _ := json.Marshal(result)
+		writeCacheable(w, r, body, playerStatsCacheDuration)
+	}
+}
+
+func (s *server) handleSearchPlayers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sport := s.directorySport(w, r)
+		if sport == nil {
+			return
+		}
+		nameInterface, _ := parseName(r.URL.Query())
+		result := s.directory.SearchPlayers(sport, nameInterface.(string))
+		if err := checkUpstreamResult(result, fmt.Sprintf("%s players", sport.Name())); err != nil {
+			logHttpError(w, err)
+			return
+		}
+		body, _ := json.Marshal(result)
+		writeCacheable(w, r, body, directoryCacheDuration)
+	}
+}
+
+// scoutSport returns the sport of the request, or writes an error and returns nil if the sport does not provide players
+func (s *server) scoutSport(w http.ResponseWriter, r *http.Request) sports.Sport {
+	sportInterface, _ := s.parseSport(mux.Vars(r))
+	sport := s.sports.ParseSportId(sportInterface.(int))
+	if !sports.SupportsScout(sport) {
+		logHttpError(w, &httpError{
+			http.StatusNotImplemented,
+			fmt.Sprintf("%s does not provide players", sport.Name()),
+		})
+		return nil
+	}
+	return sport
+}
+
+// filterGamesByTeam returns the games of a schedule in which team (stable or the league's id) is home or away
+func filterGamesByTeam(sport sports.Sport, games []map[string]interface{}, team string) []map[string]interface{} {
+	teamId := sports.ParseTeamId(sport, team)
+	filtered := make([]map[string]interface{}, 0)
+	for _, game := range games {
+		for _, side := range []string{"home", "away"} {
+			if details, ok := game[side].(map[string]interface{}); ok && fmt.Sprint(details["teamId"]) == teamId {
+				filtered = append(filtered, game)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// parseLongPollCursor parses a cursor with format `gameId:n,gameId:n`, missing or invalid entries start at 0
+func parseLongPollCursor(cursor string) map[string]int {
+	cursors := make(map[string]int)
+	if cursor == "" {
+		return cursors
+	}
+	for _, entry := range strings.Split(cursor, ",") {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if value, err := strconv.Atoi(parts[1]); err == nil {
+			cursors[parts[0]] = value
+		}
+	}
+	return cursors
+}
+
+func (s *server) parseSport(params map[string]string) (interface{}, *httpError) {
 	sportString, ok := params["sport"]
 	if !ok {
 		return nil, &httpError{
@@ -87,7 +750,7 @@ func parseSport(params map[string]string) (interface{}, *httpError) {
 			"Missing required {sport} parameter",
 		}
 	}
-	sport := sports.ParseSportString(sportString)
+	sport := s.sports.ParseSportString(sportString)
 	if sport == -1 {
 		return nil, &httpError{
 			http.StatusBadRequest,
@@ -97,6 +760,66 @@ func parseSport(params map[string]string) (interface{}, *httpError) {
 	return sport, nil
 }
 
+// parseSports parses the comma separated sports query into the sports it lists, or every sport if it is empty
+func (s *server) parseSports(query url.Values) (interface{}, *httpError) {
+	if query.Get("sports") == "" {
+		return []sports.Sport(*s.sports), nil
+	}
+	selected := make([]sports.Sport, 0)
+	for _, sportString := range strings.Split(query.Get("sports"), ",") {
+		sportId := s.sports.ParseSportString(sportString)
+		if sportId == -1 {
+			return nil, &httpError{
+				http.StatusBadRequest,
+				fmt.Sprintf("Invalid {sports} query %s", sportString),
+			}
+		}
+		sport := s.sports.ParseSportId(sportId)
+		duplicate := false
+		for _, selectedSport := range selected {
+			duplicate = duplicate || selectedSport == sport
+		}
+		if !duplicate {
+			selected = append(selected, sport)
+		}
+	}
+	return selected, nil
+}
+
+// parseCalendarRange parses the start (default today) and end (default defaultCalendarDays later) queries, yyyy-mm-dd
+func parseCalendarRange(query url.Values) (interface{}, *httpError) {
+	now := time.Now()
+	dates := calendarRange{start: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)}
+	if start := query.Get("start"); start != "" {
+		date, err := sports.CreateDateFromString(start)
+		if err != nil {
+			return nil, &httpError{
+				http.StatusBadRequest,
+				"Invalid {start} query",
+			}
+		}
+		dates.start = date
+	}
+	dates.end = dates.start.AddDate(0, 0, defaultCalendarDays-1)
+	if end := query.Get("end"); end != "" {
+		date, err := sports.CreateDateFromString(end)
+		if err != nil || date.Before(dates.start) {
+			return nil, &httpError{
+				http.StatusBadRequest,
+				"Invalid {end} query",
+			}
+		}
+		dates.end = date
+	}
+	if dates.end.Sub(dates.start) >= maxCalendarDays*24*time.Hour {
+		return nil, &httpError{
+			http.StatusBadRequest,
+			fmt.Sprintf("Calendars can span at most %d days", maxCalendarDays),
+		}
+	}
+	return dates, nil
+}
+
 func parseGameId(query url.Values) (interface{}, *httpError) {
 	gameId := query.Get("gameId")
 	if gameId == "" {
@@ -108,11 +831,74 @@ func parseGameId(query url.Values) (interface{}, *httpError) {
 	return gameId, nil
 }
 
-func (s *server) websocketUpgrade(h WebsocketHandlerFunc) http.HandlerFunc {
+func parseName(query url.Values) (interface{}, *httpError) {
+	name := strings.TrimSpace(query.Get("name"))
+	if len(name) < minSearchLength {
+		return "", &httpError{
+			http.StatusBadRequest,
+			fmt.Sprintf("{name} query must be at least %d characters", minSearchLength),
+		}
+	}
+	return name, nil
+}
+
+func parseMode(query url.Values) (interface{}, *httpError) {
+	mode := query.Get("mode")
+	switch mode {
+	case "", websocket.FullMode:
+		return websocket.FullMode, nil
+	case websocket.PatchMode:
+		return websocket.PatchMode, nil
+	}
+	return websocket.FullMode, &httpError{
+		http.StatusBadRequest,
+		"Invalid {mode} query",
+	}
+}
+
+func parseEncoding(query url.Values) (interface{}, *httpError) {
+	encoding := query.Get("encoding")
+	if encoding != "" && websocket.GetEncoding(encoding) == nil {
+		return nil, &httpError{
+			http.StatusBadRequest,
+			"Invalid {encoding} query",
+		}
+	}
+	return encoding, nil
+}
+
+// websocketUpgrade authenticates the request with a token or api key with scope, before upgrading it to a websocket
+func (s *server) websocketUpgrade(h WebsocketHandlerFunc, scope auth.Scope) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		wsClient := s.client.BaseWebsocketHandler(w, r)
+		if r = s.authenticateToken(w, r, scope); r == nil {
+			return
+		}
+		if !websocket.EncodingMatchesSubprotocol(r) {
+			logHttpError(w, &httpError{
+				http.StatusBadRequest,
+				"{encoding} query does not match the negotiated subprotocol",
+			})
+			return
+		}
+		apiKey := requestAPIKey(r)
+		if apiKey != nil && !s.quotas.AcquireSocket(apiKey) {
+			logHttpError(w, &httpError{
+				http.StatusTooManyRequests,
+				"Concurrent websocket limit reached",
+			})
+			return
+		}
+		var onClose func()
+		if apiKey != nil {
+			onClose = func() {
+				s.quotas.ReleaseSocket(apiKey)
+			}
+		}
+		wsClient := s.client.BaseWebsocketHandler(w, r, onClose)
 		if wsClient != nil {
 			h(wsClient, w, r)
+		} else if onClose != nil {
+			onClose()
 		}
 	}
 }
@@ -137,6 +923,65 @@ func (s *server) checkValidQueries(h http.HandlerFunc, paramsToValidate []Valida
 	}
 }
 
+// cors adds CORS headers to responses for requests from origins allowed by the origin policy,
+// and answers preflight requests without invoking the handler
+func (s *server) cors(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		allowed := origin != "" && s.origin.Allowed(r)
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		}
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if !allowed {
+				logHttpError(w, &httpError{
+					http.StatusForbidden,
+					"Invalid Origin",
+				})
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// writeCacheable writes body with an ETag and Cache-Control max-age,
+// or responds with 304 if the request's If-None-Match matches the ETag
+func writeCacheable(w http.ResponseWriter, r *http.Request, body []byte, maxAge time.Duration) {
+	hash := sha256.Sum256(body)
+	etag := fmt.Sprintf("\"%s\"", hex.EncodeToString(hash[:16]))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+	for _, match := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if match = strings.TrimSpace(match); match == etag || match == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	_, _ = w.Write(body)
+}
+
+// checkUpstreamResult returns the error to respond with if result (described by what) did not come from a successful upstream request
+func checkUpstreamResult(result map[string]interface{}, what string) *httpError {
+	if result == nil {
+		return &httpError{http.StatusServiceUnavailable, fmt.Sprintf("%s is temporarily unavailable", what)}
+	}
+	status := sports.UpstreamStatus(result)
+	if status == http.StatusNotFound {
+		return &httpError{http.StatusNotFound, fmt.Sprintf("%s was not found", what)}
+	} else if status < 200 || status >= 300 {
+		return &httpError{http.StatusServiceUnavailable, fmt.Sprintf("%s is temporarily unavailable", what)}
+	}
+	return nil
+}
+
 func logHttpError(w http.ResponseWriter, error *httpError) {
 	log.Errorf("Logging http.Error: %s", error.text)
 	http.Error(w, error.text, error.code)
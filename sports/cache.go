@@ -0,0 +1,197 @@
+package sports
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const liveCacheDuration = 10 * time.Second     // Schedules and games with live games
+const previewCacheDuration = 5 * time.Minute   // Today's schedule before any game has started
+const futureCacheDuration = 1 * time.Hour      // Schedules of future dates
+const completeCacheDuration = 24 * time.Hour   // Schedules and games that are complete
+const StandingsCacheDuration = 5 * time.Minute // Standings are also invalidated when games go final
+const maxCacheEntries = 1000                   // Expired entries are removed once the cache grows past this
+
+type cacheEntry struct {
+	result  map[string]interface{}
+	expires time.Time
+}
+
+// cachedSport wraps a Sport so that results are reused until they expire, see ScheduleCacheDuration and PlayByPlayCacheDuration
+type cachedSport struct {
+	Sport
+	entries map[string]cacheEntry
+	mutex   sync.Mutex
+}
+
+func cacheSport(sport Sport) Sport {
+	return &cachedSport{
+		Sport:   sport,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (c *cachedSport) Schedule(params url.Values) map[string]interface{} {
+	return c.call("schedule", params, c.Sport.Schedule, func(result map[string]interface{}) time.Duration {
+		return ScheduleCacheDuration(c, result)
+	})
+}
+
+func (c *cachedSport) PlayByPlay(params url.Values) map[string]interface{} {
+	return c.call("playbyplay", params, c.Sport.PlayByPlay, PlayByPlayCacheDuration)
+}
+
+func (c *cachedSport) BoxScore(params url.Values) map[string]interface{} {
+	boxScorer, ok := c.Sport.(BoxScorer)
+	if !ok {
+		return nil
+	}
+	return c.call("boxscore", params, boxScorer.BoxScore, BoxScoreCacheDuration)
+}
+
+func (c *cachedSport) Standings(params url.Values) map[string]interface{} {
+	ranker, ok := c.Sport.(Ranker)
+	if !ok {
+		return nil
+	}
+	return c.call("standings", params, ranker.Standings, func(map[string]interface{}) time.Duration {
+		return StandingsCacheDuration
+	})
+}
+
+// Teams, Roster, Player and PlayerStats are not cached in memory, they are stored in the database by the directory package
+func (c *cachedSport) Teams(params url.Values) map[string]interface{} {
+	directory, ok := c.Sport.(Directory)
+	if !ok {
+		return nil
+	}
+	return directory.Teams(params)
+}
+
+func (c *cachedSport) Roster(params url.Values) map[string]interface{} {
+	directory, ok := c.Sport.(Directory)
+	if !ok {
+		return nil
+	}
+	return directory.Roster(params)
+}
+
+func (c *cachedSport) Player(params url.Values) map[string]interface{} {
+	scout, ok := c.Sport.(Scout)
+	if !ok {
+		return nil
+	}
+	return scout.Player(params)
+}
+
+func (c *cachedSport) PlayerStats(params url.Values) map[string]interface{} {
+	scout, ok := c.Sport.(Scout)
+	if !ok {
+		return nil
+	}
+	return scout.PlayerStats(params)
+}
+
+func (c *cachedSport) wrapped() []Sport {
+	return []Sport{c.Sport}
+}
+
+func (c *cachedSport) call(method string, params url.Values, request func(url.Values) map[string]interface{},
+	duration func(map[string]interface{}) time.Duration) map[string]interface{} {
+	key := method + "?" + params.Encode()
+	c.mutex.Lock()
+	entry, ok := c.entries[key]
+	c.mutex.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return copyResult(entry.result) // Entries are shared, callers may modify their result
+	}
+	result := request(params)
+	if result == nil { // Upstream is unavailable
+		return nil
+	}
+	if !succeeded(result) { // Failures are returned without being cached, so the next call requests it again
+		return result
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.entries) >= maxCacheEntries {
+		c.removeExpired()
+	}
+	c.entries[key] = cacheEntry{result: copyResult(result), expires: time.Now().Add(duration(result))}
+	return result
+}
+
+// invalidate removes the entries of method
+func (c *cachedSport) invalidate(method string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, method+"?") {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *cachedSport) removeExpired() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// ScheduleCacheDuration returns how long a schedule result of sport stays valid based on the state of its games
+func ScheduleCacheDuration(sport Sport, schedule map[string]interface{}) time.Duration {
+	games := CheckActiveGames(sport, schedule)
+	if len(games) == 0 {
+		return previewCacheDuration
+	}
+	complete, future := true, true
+	for _, game := range games {
+		if game.ScheduleState == Live || game.ScheduleState == Intermission {
+			return liveCacheDuration
+		}
+		complete = complete && (game.ScheduleState == Complete || game.ScheduleState == Postponed)
+		future = future && game.ScheduleState == Preview && time.Until(game.StartTime) > 24*time.Hour
+	}
+	if complete {
+		return completeCacheDuration
+	} else if future {
+		return futureCacheDuration
+	}
+	return previewCacheDuration
+}
+
+// PlayByPlayCacheDuration returns how long a play by play result stays valid based on the state of its game
+func PlayByPlayCacheDuration(playbyplay map[string]interface{}) time.Duration {
+	if metadata, ok := playbyplay["metadata"].(map[string]interface{}); ok {
+		if state, ok := metadata["state"].(ScheduleState); ok && state == Complete {
+			return completeCacheDuration
+		}
+	}
+	return liveCacheDuration
+}
+
+// InvalidateStandings removes the cached standings of sport, so they are requested again
+func InvalidateStandings(sport Sport) {
+	invalidate(sport, "standings")
+}
+
+func invalidate(sport Sport, method string) {
+	if c, ok := sport.(*cachedSport); ok {
+		c.invalidate(method)
+	}
+	if w, ok := sport.(wrapper); ok {
+		for _, wrappedSport := range w.wrapped() {
+			invalidate(wrappedSport, method)
+		}
+	}
+}
+
+// BoxScoreCacheDuration returns how long a box score result stays valid based on the state of its game
+func BoxScoreCacheDuration(boxScore map[string]interface{}) time.Duration {
+	return PlayByPlayCacheDuration(boxScore)
+}
@@ -0,0 +1,81 @@
+package sports
+
+import (
+	"net/url"
+)
+
+// Capabilities are requests that only some sports support, the sports that wrap other sports
+// (cachedSport, limitedSport and compositeSport) implement every capability and return nil if the sports they wrap do not.
+// Use the Supports functions to check if a sport actually provides a capability.
+
+// BoxScorer is a Sport that provides per player and team stat lines of a game
+// params[] gameId
+type BoxScorer interface {
+	BoxScore(params url.Values) map[string]interface{}
+}
+
+// Ranker is a Sport that provides league standings, grouped by league, conference and division
+type Ranker interface {
+	Standings(params url.Values) map[string]interface{}
+}
+
+// Directory is a Sport that provides its teams and their rosters.
+// Teams returns every team in result["content"], Roster returns the players of params[] teamId (the league's id) in result["content"]
+type Directory interface {
+	Teams(params url.Values) map[string]interface{}
+	Roster(params url.Values) map[string]interface{}
+}
+
+// Scout is a Sport that provides player profiles and stats.
+// Player returns the profile of params[] playerId in result["content"],
+// PlayerStats returns the season by season and career stats of params[] playerId in result["content"]
+type Scout interface {
+	Player(params url.Values) map[string]interface{}
+	PlayerStats(params url.Values) map[string]interface{}
+}
+
+// wrapper is implemented by sports that wrap other sports
+type wrapper interface {
+	wrapped() []Sport
+}
+
+func SupportsBoxScore(sport Sport) bool {
+	return supports(sport, func(sport Sport) bool {
+		_, ok := sport.(BoxScorer)
+		return ok
+	})
+}
+
+func SupportsStandings(sport Sport) bool {
+	return supports(sport, func(sport Sport) bool {
+		_, ok := sport.(Ranker)
+		return ok
+	})
+}
+
+func SupportsDirectory(sport Sport) bool {
+	return supports(sport, func(sport Sport) bool {
+		_, ok := sport.(Directory)
+		return ok
+	})
+}
+
+func SupportsScout(sport Sport) bool {
+	return supports(sport, func(sport Sport) bool {
+		_, ok := sport.(Scout)
+		return ok
+	})
+}
+
+// supports checks if capable is true for sport, or for any sport it wraps
+func supports(sport Sport, capable func(Sport) bool) bool {
+	if w, ok := sport.(wrapper); ok {
+		for _, wrappedSport := range w.wrapped() {
+			if supports(wrappedSport, capable) {
+				return true
+			}
+		}
+		return false
+	}
+	return capable(sport)
+}
@@ -0,0 +1,231 @@
+package sports
+
+import (
+	"encoding/json"
+	"github.com/ngaut/log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const healthCheckInterval = 1 * time.Minute  // An unhealthy provider is tried in its order again after this long
+const healthProbeInterval = 15 * time.Second // Unhealthy providers are probed this often, until they are healthy or tried again
+const staleDuration = 5 * time.Minute        // A live game whose play by play has not changed for this long is stale
+
+// Provider is a source of a sport's data, a sport can be backed by multiple providers
+type Provider interface {
+	Sport
+	Provider() string
+}
+
+type providerHealth struct {
+	source      Provider
+	healthy     bool
+	retryAt     time.Time            // When an unhealthy provider is tried in its order again
+	lastResults map[string]string    // Last play by play of each game (JSON), to detect stale data
+	lastChanges map[string]time.Time // When the play by play of each game last changed
+	mutex       sync.Mutex
+}
+
+// compositeSport tries its providers in order, skipping unhealthy providers,
+// failing over when a provider errors or returns stale data. The provider that served a result is in its metadata.
+// Unhealthy providers are probed in the background with the limits of the limitedSport wrapping the composite.
+type compositeSport struct {
+	providers []*providerHealth
+}
+
+func composeSport(providers ...Provider) Sport {
+	composite := &compositeSport{
+		providers: make([]*providerHealth, len(providers)),
+	}
+	for i, provider := range providers {
+		composite.providers[i] = &providerHealth{
+			source:      provider,
+			healthy:     true,
+			lastResults: make(map[string]string),
+			lastChanges: make(map[string]time.Time),
+		}
+	}
+	return composite
+}
+
+func (c *compositeSport) Name() string {
+	return c.providers[0].source.Name()
+}
+
+func (c *compositeSport) ParseScheduleState(statusCode int) ScheduleState {
+	return c.providers[0].source.ParseScheduleState(statusCode)
+}
+
+func (c *compositeSport) DefaultTimeString() string {
+	return c.providers[0].source.DefaultTimeString()
+}
+
+func (c *compositeSport) Schedule(params url.Values) map[string]interface{} {
+	return c.call(c.providers, func(provider *providerHealth) (map[string]interface{}, bool) {
+		result := provider.source.Schedule(params)
+		return result, succeeded(result)
+	})
+}
+
+func (c *compositeSport) PlayByPlay(params url.Values) map[string]interface{} {
+	return c.call(c.providers, func(provider *providerHealth) (map[string]interface{}, bool) {
+		result := provider.source.PlayByPlay(params)
+		return result, succeeded(result) && !provider.stale(params.Get("gameId"), result)
+	})
+}
+
+func (c *compositeSport) BoxScore(params url.Values) map[string]interface{} {
+	return c.call(c.capable(SupportsBoxScore), func(provider *providerHealth) (map[string]interface{}, bool) {
+		result := provider.source.(BoxScorer).BoxScore(params)
+		return result, succeeded(result)
+	})
+}
+
+func (c *compositeSport) Standings(params url.Values) map[string]interface{} {
+	return c.call(c.capable(SupportsStandings), func(provider *providerHealth) (map[string]interface{}, bool) {
+		result := provider.source.(Ranker).Standings(params)
+		return result, succeeded(result)
+	})
+}
+
+func (c *compositeSport) Teams(params url.Values) map[string]interface{} {
+	return c.call(c.capable(SupportsDirectory), func(provider *providerHealth) (map[string]interface{}, bool) {
+		result := provider.source.(Directory).Teams(params)
+		return result, succeeded(result)
+	})
+}
+
+func (c *compositeSport) Roster(params url.Values) map[string]interface{} {
+	return c.call(c.capable(SupportsDirectory), func(provider *providerHealth) (map[string]interface{}, bool) {
+		result := provider.source.(Directory).Roster(params)
+		return result, succeeded(result)
+	})
+}
+
+func (c *compositeSport) Player(params url.Values) map[string]interface{} {
+	return c.call(c.capable(SupportsScout), func(provider *providerHealth) (map[string]interface{}, bool) {
+		result := provider.source.(Scout).Player(params)
+		return result, succeeded(result)
+	})
+}
+
+func (c *compositeSport) PlayerStats(params url.Values) map[string]interface{} {
+	return c.call(c.capable(SupportsScout), func(provider *providerHealth) (map[string]interface{}, bool) {
+		result := provider.source.(Scout).PlayerStats(params)
+		return result, succeeded(result)
+	})
+}
+
+func (c *compositeSport) wrapped() []Sport {
+	sports := make([]Sport, len(c.providers))
+	for i, provider := range c.providers {
+		sports[i] = provider.source
+	}
+	return sports
+}
+
+// capable returns the providers that support a capability
+func (c *compositeSport) capable(supports func(Sport) bool) []*providerHealth {
+	providers := make([]*providerHealth, 0, len(c.providers))
+	for _, provider := range c.providers {
+		if supports(provider.source) {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// call requests each healthy provider of providers in order until one succeeds, and then each unhealthy provider.
+// If every provider fails, the last result is returned.
+func (c *compositeSport) call(providers []*providerHealth, request func(provider *providerHealth) (map[string]interface{}, bool)) map[string]interface{} {
+	var result map[string]interface{}
+	tried := make(map[*providerHealth]bool)
+	for _, healthy := range []bool{true, false} {
+		for _, provider := range providers {
+			if tried[provider] || provider.isHealthy() != healthy { // A provider that failed is not requested twice
+				continue
+			}
+			tried[provider] = true
+			providerResult, ok := request(provider)
+			if providerResult != nil {
+				result = providerResult
+				setProvider(result, provider.source.Provider())
+			}
+			if ok {
+				provider.setHealthy(true)
+				return result
+			}
+			log.Errorf("%s provider %s failed, failing over", provider.source.Name(), provider.source.Provider())
+			if status := UpstreamStatus(providerResult); status < 400 || status >= 500 || status == http.StatusTooManyRequests {
+				provider.setHealthy(false) // Other 4xx are caused by the request, not the provider
+			}
+		}
+	}
+	return result
+}
+
+// checkHealth probes the schedule of every unhealthy provider, marking it healthy if the probe succeeds.
+// A probe is only sent if allow grants it, so probes count towards the sport's upstream limit.
+// Goroutine function
+func (c *compositeSport) checkHealth(allow func() bool) {
+	ticker := time.NewTicker(healthProbeInterval)
+	for range ticker.C {
+		for _, provider := range c.providers {
+			if provider.isHealthy() || !allow() {
+				continue
+			}
+			if succeeded(provider.source.Schedule(nil)) {
+				provider.setHealthy(true)
+			}
+		}
+	}
+}
+
+// isHealthy checks if the provider is healthy, or is unhealthy but due to be tried again
+func (p *providerHealth) isHealthy() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.healthy || time.Now().After(p.retryAt)
+}
+
+func (p *providerHealth) setHealthy(healthy bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if healthy && !p.healthy {
+		log.Debugf("%s provider %s is healthy", p.source.Name(), p.source.Provider())
+	}
+	if !healthy {
+		p.retryAt = time.Now().Add(healthCheckInterval)
+	}
+	p.healthy = healthy
+}
+
+// stale checks if the play by play of a live game has not changed for staleDuration
+func (p *providerHealth) stale(gameId string, playbyplay map[string]interface{}) bool {
+	if metadata, ok := playbyplay["metadata"].(map[string]interface{}); !ok || metadata["state"] != Live {
+		return false
+	}
+	b, _ := json.Marshal(playbyplay["game"])
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.lastResults[gameId] != string(b) {
+		p.lastResults[gameId] = string(b)
+		p.lastChanges[gameId] = time.Now()
+		return false
+	}
+	return time.Since(p.lastChanges[gameId]) > staleDuration
+}
+
+// succeeded checks if result came from a successful upstream request
+func succeeded(result map[string]interface{}) bool {
+	status := UpstreamStatus(result)
+	return result != nil && status >= 200 && status < 300
+}
+
+func setProvider(result map[string]interface{}, provider string) {
+	if metadata, ok := result["metadata"].(map[string]interface{}); ok {
+		metadata["provider"] = provider
+	}
+}
@@ -0,0 +1,54 @@
+package sports
+
+// EventType is a sport independent classification of a play
+type EventType string
+
+const (
+	ScoreChange  EventType = "scoreChange"
+	LeadChange   EventType = "leadChange" // Leading team after the play differs from before, and the game is not tied
+	PeriodStart  EventType = "periodStart"
+	PeriodEnd    EventType = "periodEnd"
+	Penalty      EventType = "penalty" // Penalties and fouls
+	Timeout      EventType = "timeout"
+	Substitution EventType = "substitution"
+	Challenge    EventType = "challenge" // Coach's challenges and video reviews
+	GameFinal    EventType = "gameFinal"
+)
+
+// buildEvent creates an event of eventType from a play result built by a sport adapter
+func buildEvent(eventType EventType, play map[string]interface{}, period int, home int, away int) map[string]interface{} {
+	event := make(map[string]interface{})
+	event["type"] = eventType
+	event["period"] = period
+	event["periodTime"] = play["periodTime"]
+	event["description"] = play["description"]
+	event["teamId"] = play["teamId"]
+	if participants, ok := play["participants"]; ok {
+		event["participants"] = participants
+	}
+	event["score"] = map[string]interface{}{"home": home, "away": away}
+	return event
+}
+
+// buildScoreEvents creates the score change and lead change events for a play that changed the score
+func buildScoreEvents(play map[string]interface{}, period int, prevHome int, prevAway int, home int, away int) []map[string]interface{} {
+	events := make([]map[string]interface{}, 0, 2)
+	if prevHome == home && prevAway == away {
+		return events
+	}
+	events = append(events, buildEvent(ScoreChange, play, period, home, away))
+	if leader := compareScores(home, away); leader != 0 && leader != compareScores(prevHome, prevAway) {
+		events = append(events, buildEvent(LeadChange, play, period, home, away))
+	}
+	return events
+}
+
+// compareScores returns 1 if home is leading, -1 if away is leading and 0 if tied
+func compareScores(home int, away int) int {
+	if home > away {
+		return 1
+	} else if away > home {
+		return -1
+	}
+	return 0
+}
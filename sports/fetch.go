@@ -0,0 +1,39 @@
+package sports
+
+import (
+	"encoding/json"
+	"github.com/ngaut/log"
+	"net/http"
+	"time"
+)
+
+const fetchTimeout = 10 * time.Second
+
+var fetchClient = &http.Client{Timeout: fetchTimeout}
+
+// fetchJSON requests url and decodes its JSON body into v, for endpoints that are not covered by gonhl or gonba.
+// Returns the response status code, or 0 if the request failed.
+func fetchJSON(url string, headers map[string]string, v interface{}) int {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		log.Errorf("Error creating request for %s: %s", url, err)
+		return 0
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	response, err := fetchClient.Do(request)
+	if err != nil {
+		log.Errorf("Error requesting %s: %s", url, err)
+		return 0
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return response.StatusCode
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		log.Errorf("Error decoding %s: %s", url, err)
+		return 0
+	}
+	return response.StatusCode
+}
@@ -0,0 +1,166 @@
+package sports
+
+import (
+	"context"
+	"github.com/ngaut/log"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const minUpstreamBackoff = 1 * time.Second
+const maxUpstreamBackoff = 2 * time.Minute
+
+// UpstreamLimit is the rate of requests a sport may make to its league's api
+type UpstreamLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+var DefaultUpstreamLimit = UpstreamLimit{RequestsPerSecond: 2, Burst: 5}
+
+// limitedSport wraps a Sport so that its upstream requests are rate limited,
+// identical concurrent requests share one in flight request,
+// and requests are not made while backing off after the upstream returned 429 or 5xx.
+type limitedSport struct {
+	Sport
+	limiter      *rate.Limiter
+	group        singleflight.Group
+	backoff      time.Duration
+	backoffUntil time.Time
+	mutex        sync.Mutex
+}
+
+// healthChecker is a Sport that checks the health of its providers in the background, requesting them if allow grants it
+type healthChecker interface {
+	checkHealth(allow func() bool)
+}
+
+func limitSport(sport Sport, limit UpstreamLimit) Sport {
+	limited := &limitedSport{
+		Sport:   sport,
+		limiter: rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), limit.Burst),
+	}
+	if checker, ok := sport.(healthChecker); ok {
+		go checker.checkHealth(limited.allowProbe)
+	}
+	return limited
+}
+
+func (l *limitedSport) Schedule(params url.Values) map[string]interface{} {
+	return l.call("schedule", params, l.Sport.Schedule)
+}
+
+func (l *limitedSport) PlayByPlay(params url.Values) map[string]interface{} {
+	return l.call("playbyplay", params, l.Sport.PlayByPlay)
+}
+
+func (l *limitedSport) BoxScore(params url.Values) map[string]interface{} {
+	boxScorer, ok := l.Sport.(BoxScorer)
+	if !ok {
+		return nil
+	}
+	return l.call("boxscore", params, boxScorer.BoxScore)
+}
+
+func (l *limitedSport) Standings(params url.Values) map[string]interface{} {
+	ranker, ok := l.Sport.(Ranker)
+	if !ok {
+		return nil
+	}
+	return l.call("standings", params, ranker.Standings)
+}
+
+func (l *limitedSport) Teams(params url.Values) map[string]interface{} {
+	directory, ok := l.Sport.(Directory)
+	if !ok {
+		return nil
+	}
+	return l.call("teams", params, directory.Teams)
+}
+
+func (l *limitedSport) Roster(params url.Values) map[string]interface{} {
+	directory, ok := l.Sport.(Directory)
+	if !ok {
+		return nil
+	}
+	return l.call("roster", params, directory.Roster)
+}
+
+func (l *limitedSport) Player(params url.Values) map[string]interface{} {
+	scout, ok := l.Sport.(Scout)
+	if !ok {
+		return nil
+	}
+	return l.call("player", params, scout.Player)
+}
+
+func (l *limitedSport) PlayerStats(params url.Values) map[string]interface{} {
+	scout, ok := l.Sport.(Scout)
+	if !ok {
+		return nil
+	}
+	return l.call("playerstats", params, scout.PlayerStats)
+}
+
+func (l *limitedSport) wrapped() []Sport {
+	return []Sport{l.Sport}
+}
+
+// call makes an upstream request through the limiter, returning nil while backing off
+func (l *limitedSport) call(method string, params url.Values, request func(url.Values) map[string]interface{}) map[string]interface{} {
+	if l.backingOff() {
+		return nil
+	}
+	result, _, shared := l.group.Do(method+"?"+params.Encode(), func() (interface{}, error) {
+		if err := l.limiter.Wait(context.Background()); err != nil {
+			return nil, err
+		}
+		result := request(params)
+		l.updateBackoff(UpstreamStatus(result))
+		return result, nil
+	})
+	if result == nil || l.backingOff() {
+		return nil
+	}
+	if shared { // Every caller of the in flight request received the same result
+		return copyResult(result.(map[string]interface{}))
+	}
+	return result.(map[string]interface{})
+}
+
+// allowProbe takes a request from the limiter for a background probe, if one is available and not backing off
+func (l *limitedSport) allowProbe() bool {
+	return !l.backingOff() && l.limiter.Allow()
+}
+
+func (l *limitedSport) backingOff() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return time.Now().Before(l.backoffUntil)
+}
+
+// updateBackoff doubles the backoff when status is 429, 5xx or StatusUnreachable, and resets it otherwise
+// (including results without a status)
+func (l *limitedSport) updateBackoff(status int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if status != StatusUnreachable && status != http.StatusTooManyRequests && status < http.StatusInternalServerError {
+		l.backoff = 0
+		return
+	}
+	if l.backoff == 0 {
+		l.backoff = minUpstreamBackoff
+	} else if l.backoff < maxUpstreamBackoff {
+		l.backoff *= 2
+	}
+	l.backoffUntil = time.Now().Add(l.backoff)
+	if status == StatusUnreachable {
+		log.Errorf("%s upstream could not be reached, backing off for %s", l.Name(), l.backoff.String())
+	} else {
+		log.Errorf("%s upstream returned %d, backing off for %s", l.Name(), status, l.backoff.String())
+	}
+}
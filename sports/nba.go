@@ -4,18 +4,51 @@ import (
 	"fmt"
 	"github.com/henrymxu/gonba"
 	"github.com/ngaut/log"
+	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+const nbaRegulationPeriods = 4
+const nbaMaxPeriods = 14 // Regulation and up to 10 overtimes
+
 type nba struct {
-	client *gonba.Client
+	client       *gonba.Client
+	rosters      map[string]nbaRoster // Players of each game being played, by game id
+	rostersMutex sync.Mutex
+}
+
+// nbaPlay is a play with its participants, whose names, numbers and teams may not be known yet (see resolveParticipants)
+type nbaPlay struct {
+	gonba.Play
+	participants []nbaParticipant
+}
+
+type nbaParticipant struct {
+	role     ParticipantRole
+	playerId string
+	name     string
+	teamId   string
+	team     string
 }
 
+type nbaRosterPlayer struct {
+	name   string
+	number string
+	teamId string
+	team   string
+}
+
+// nbaRoster is the players of a game by id, from the game's box score
+type nbaRoster map[string]nbaRosterPlayer
+
 func InitNBA() *nba {
 	return &nba{
-		client: gonba.NewClient(),
+		client:  gonba.NewClient(),
+		rosters: make(map[string]nbaRoster),
 	}
 }
 
@@ -23,6 +56,10 @@ func (n *nba) Name() string {
 	return "nba"
 }
 
+func (n *nba) Provider() string {
+	return "data.nba.com"
+}
+
 func (n *nba) Schedule(params url.Values) map[string]interface{} {
 	date := time.Now()
 	if params != nil {
@@ -36,25 +73,45 @@ func (n *nba) Schedule(params url.Values) map[string]interface{} {
 			}
 		}
 	}
-	schedule, _ := n.client.GetSchedule(date)
-	return buildResultFromNBASchedule(&schedule)
+	schedule, status := n.client.GetSchedule(date)
+	return setUpstreamStatus(buildResultFromNBASchedule(&schedule), status)
 }
 
+// PlayByPlay returns the plays after the cursor in params["date"] (see nbaCursor), fetching one period at a time
 func (n *nba) PlayByPlay(params url.Values) map[string]interface{} {
 	gameId := params.Get("gameId")
-	date := time.Now()
-	lastCheck := params.Get("date")
-	if lastCheck == "" {
-		lastCheck = n.DefaultTimeString()
+	date := n.gameDate(gameId, params.Get("gameDate"))
+	result := buildResultFromNBAPlayByPlay(parseNBACursor(params.Get("date")), func(period int) ([]nbaPlay, int) {
+		playByPlay, status := n.client.GetPlayByPlayV2(date, gameId, period)
+		return buildNBAPlaysFromPlayByPlay(&playByPlay), status
+	})
+	return n.resolveParticipants(gameId, result, func() (nbaBoxScoreTeam, nbaBoxScoreTeam, int) {
+		home, away, _, status := n.fetchBoxScore(gameId, date)
+		return home, away, status
+	})
+}
+
+// gameDate resolves the US Eastern date of a game, which is the date data.nba.com files it under,
+// from gameDate (the scheduled start time) or else by looking for the game in the surrounding schedules
+func (n *nba) gameDate(gameId string, gameDate string) time.Time {
+	eastern := easternLocation()
+	if gameDate != "" {
+		date, err := CreateDateFromDetailedString(gameDate)
+		if err == nil {
+			return date.In(eastern)
+		}
+		log.Errorf("NBA game date error: %v", err)
 	}
-	period, _ := strconv.Atoi(params.Get("period"))
-	var playByPlay gonba.PlayByPlayV2
-	if period != 0 {
-		playByPlay, _ = n.client.GetPlayByPlayV2(date, gameId, period)
-	} else { // Get all initial plays
-		playByPlay, _ = n.client.GetPlayByPlayV2All(date, gameId)
+	today := time.Now().In(eastern)
+	for _, date := range []time.Time{today, today.AddDate(0, 0, -1), today.AddDate(0, 0, 1)} {
+		schedule, _ := n.client.GetSchedule(date)
+		for _, game := range schedule.Games {
+			if game.GameId == gameId {
+				return date
+			}
+		}
 	}
-	return buildResultFromNBAPlayByPlay(&playByPlay, lastCheck, period)
+	return today
 }
 
 func (n *nba) ParseScheduleState(statusCode int) ScheduleState {
@@ -67,7 +124,39 @@ func (n *nba) ParseScheduleState(statusCode int) ScheduleState {
 }
 
 func (n *nba) DefaultTimeString() string {
-	return "12:00"
+	return nbaCursor{period: 1}.String()
+}
+
+// nbaCursor is the position of the next play of a game, sequence is the index of the play within its period
+type nbaCursor struct {
+	period   int
+	sequence int
+}
+
+func parseNBACursor(cursor string) nbaCursor {
+	result := nbaCursor{period: 1}
+	parts := strings.Split(cursor, ":")
+	if len(parts) != 2 {
+		return result
+	}
+	period, err := strconv.Atoi(parts[0])
+	sequence, err2 := strconv.Atoi(parts[1])
+	if err != nil || err2 != nil || period < 1 || sequence < 0 {
+		return result
+	}
+	return nbaCursor{period: period, sequence: sequence}
+}
+
+func (c nbaCursor) String() string {
+	return fmt.Sprintf("%d:%d", c.period, c.sequence)
+}
+
+func easternLocation() *time.Location {
+	eastern, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.UTC
+	}
+	return eastern
 }
 
 func buildResultFromNBASchedule(schedule *gonba.Schedule) map[string]interface{} {
@@ -96,6 +185,7 @@ func buildGameFromGames(scheduledGame gonba.Game) map[string]interface{} {
 
 func parseNBATeam(team gonba.Team) map[string]interface{} {
 	result := make(map[string]interface{})
+	result["id"] = TeamId("nba", team.Id)
 	result["teamId"] = team.Id
 	result["name"] = fmt.Sprintf("%s %s", team.City, team.Name)
 	result["abbr"] = team.Abbr
@@ -104,50 +194,179 @@ func parseNBATeam(team gonba.Team) map[string]interface{} {
 	return result
 }
 
-func buildResultFromNBAPlayByPlay(playByPlay *gonba.PlayByPlayV2, lastCheck string, period int) map[string]interface{} {
+// buildResultFromNBAPlayByPlay builds the plays after cursor, fetching each period with fetchPeriod until a period
+// has not ended (or has not started). Overtime periods are fetched like any other period.
+// The cursor stays in the last period with plays, so the score and clock are known between periods.
+func buildResultFromNBAPlayByPlay(cursor nbaCursor, fetchPeriod func(period int) ([]nbaPlay, int)) map[string]interface{} {
 	result := make(map[string]interface{})
-	plays := make([]map[string]interface{}, 0, len(playByPlay.Plays))
-	var lastPlay gonba.Play
-	for _, play := range playByPlay.Plays {
-		if pastLastCheck(play.Clock, lastCheck) {
+	plays := make([]map[string]interface{}, 0)
+	events := make([]map[string]interface{}, 0)
+	lastPlay := gonba.Play{Clock: "12:00"}
+	period := cursor.period
+	gameState := Live
+	upstreamStatus := 0
+	for ; period <= nbaMaxPeriods; period++ {
+		periodPlays, status := fetchPeriod(period)
+		if period == cursor.period {
+			upstreamStatus = status
+		}
+		if status != http.StatusOK || len(periodPlays) == 0 { // Period has not started
+			break
+		}
+		sequence := 0
+		if period == cursor.period && cursor.sequence <= len(periodPlays) {
+			sequence = cursor.sequence
+		}
+		for i, play := range periodPlays[sequence:] {
+			i += sequence
+			prevPlay := play
+			if i > 0 {
+				prevPlay = periodPlays[i-1]
+			}
 			playResult := make(map[string]interface{})
 			playResult["description"] = play.Formatted.Description
 			playResult["typeId"] = play.EventMsgType
+			playResult["period"] = period
+			playResult["sequence"] = i
 			playResult["periodTime"] = play.Clock
 			playResult["teamId"] = play.TeamID
 			playResult["playerId"] = play.PersonID
+			playResult["participants"] = buildNBAParticipants(play.participants)
 			plays = append(plays, playResult)
+			events = append(events, buildEventsFromNBAPlay(&play.Play, &prevPlay.Play, playResult, period)...)
 		}
-		lastPlay = play
-	}
-	result["plays"] = plays
-	if len(playByPlay.Plays) == 0 {
-		lastPlay = gonba.Play{
-			Clock: lastCheck,
+		lastPlay = periodPlays[len(periodPlays)-1].Play
+		cursor = nbaCursor{period: period, sequence: len(periodPlays)}
+		if lastPlay.EventMsgType != 13 { // Period is still in progress, otherwise continue with the next period
+			break
 		}
-	}
-	status := map[string]interface{}{"period": period, "periodTimeRemaining": lastPlay.Clock}
-	gameState := Live
-	if lastPlay.EventMsgType == 13 { // End of Quarter Event
-		status["period"] = status["period"].(int) + 1
-		if status["period"].(int) > 4 {
-			if lastPlay.HTeamScore != lastPlay.VTeamScore {
-				gameState = Complete
-			}
+		if nbaGameOver(period, &lastPlay) {
+			gameState = Complete
+			break
 		}
 	}
+	result["plays"] = plays
+	result["events"] = events
+	status := map[string]interface{}{"period": cursor.period, "periodTimeRemaining": lastPlay.Clock}
 	game := make(map[string]interface{})
 	game["status"] = status
 	game["home"] = map[string]interface{}{"score": lastPlay.HTeamScore}
 	game["away"] = map[string]interface{}{"score": lastPlay.VTeamScore}
 	result["game"] = game
-	result["metadata"] = map[string]interface{}{"state": gameState, "lastCheck": lastPlay.Clock}
+	result["metadata"] = map[string]interface{}{"state": gameState, "lastCheck": cursor.String()}
+	return setUpstreamStatus(result, upstreamStatus)
+}
+
+// buildNBAPlaysFromPlayByPlay converts data.nba.com plays, which only have their primary participant
+func buildNBAPlaysFromPlayByPlay(playByPlay *gonba.PlayByPlayV2) []nbaPlay {
+	plays := make([]nbaPlay, len(playByPlay.Plays))
+	for i, play := range playByPlay.Plays {
+		plays[i] = nbaPlay{Play: play}
+		if roles := nbaParticipantRoles[play.EventMsgType]; len(roles) > 0 && play.PersonID != "" && play.PersonID != "0" {
+			plays[i].participants = []nbaParticipant{{role: roles[0], playerId: play.PersonID, teamId: play.TeamID}}
+		}
+	}
+	return plays
+}
+
+// nbaParticipantRoles are the roles of the first, second and third players of a play by EventMsgType
+var nbaParticipantRoles = map[int][]ParticipantRole{
+	1:  {Scorer, Assister},              // Made shot
+	2:  {Shooter, "", Blocker},          // Missed shot
+	3:  {Shooter},                       // Free throw
+	4:  {Rebounder},                     // Rebound
+	5:  {TurnedOver, Stealer},           // Turnover
+	6:  {Penalized, DrewPenalty},        // Foul
+	7:  {Penalized},                     // Violation
+	8:  {SubstitutedOut, SubstitutedIn}, // Substitution
+	10: {Winner, Loser, Player},         // Jump ball, the third player gains possession
+}
+
+func buildNBAParticipants(participants []nbaParticipant) []map[string]interface{} {
+	result := make([]map[string]interface{}, len(participants))
+	for i, participant := range participants {
+		result[i] = buildParticipant(participant.role, participant.playerId, participant.name, "", participant.team, participant.teamId)
+	}
 	return result
 }
 
-func pastLastCheck(playTime string, lastCheck string) bool {
-	playTimeTime, _ := time.Parse("3:04", playTime)
-	lastCheckTime, _ := time.Parse("3:04", lastCheck)
-	return playTimeTime.Before(lastCheckTime)
-	//return true
-}
\ No newline at end of file
+// resolveParticipants fills the names, numbers and teams of the participants of result's plays from the game's roster.
+// The roster is requested with fetchTeams when the game has a participant that is not in it.
+func (n *nba) resolveParticipants(gameId string, result map[string]interface{},
+	fetchTeams func() (nbaBoxScoreTeam, nbaBoxScoreTeam, int)) map[string]interface{} {
+	n.rostersMutex.Lock()
+	defer n.rostersMutex.Unlock()
+	roster := n.rosters[gameId]
+	refreshed := false
+	plays, _ := result["plays"].([]map[string]interface{})
+	for _, play := range plays {
+		participants, _ := play["participants"].([]map[string]interface{})
+		for _, participant := range participants {
+			playerId := participant["playerId"].(string)
+			rosterPlayer, ok := roster[playerId]
+			if !ok && !refreshed {
+				refreshed = true
+				if home, away, status := fetchTeams(); status == http.StatusOK {
+					roster = buildNBARoster(&home, &away)
+					n.rosters[gameId] = roster
+				}
+				rosterPlayer, ok = roster[playerId]
+			}
+			if !ok {
+				continue
+			}
+			if participant["name"] == "" {
+				participant["name"] = rosterPlayer.name
+			}
+			participant["number"] = rosterPlayer.number
+			participant["team"] = rosterPlayer.team
+			participant["teamId"] = rosterPlayer.teamId
+		}
+	}
+	if metadata, ok := result["metadata"].(map[string]interface{}); ok && metadata["state"] == Complete {
+		delete(n.rosters, gameId)
+	}
+	return result
+}
+
+func buildNBARoster(teams ...*nbaBoxScoreTeam) nbaRoster {
+	roster := make(nbaRoster)
+	for _, team := range teams {
+		for _, player := range team.players {
+			roster[player.id] = nbaRosterPlayer{name: player.name, number: player.number, teamId: team.teamId, team: team.abbr}
+		}
+	}
+	return roster
+}
+
+// nbaGameOver checks if the game is over after period ends with lastPlay, regulation is 4 periods and overtime continues until the scores differ
+func nbaGameOver(period int, lastPlay *gonba.Play) bool {
+	return period >= nbaRegulationPeriods && lastPlay.HTeamScore != lastPlay.VTeamScore
+}
+
+// buildEventsFromNBAPlay maps a play to its typed events, prevPlay is used to detect score changes
+func buildEventsFromNBAPlay(play *gonba.Play, prevPlay *gonba.Play, playResult map[string]interface{}, period int) []map[string]interface{} {
+	events := buildScoreEvents(playResult, period, prevPlay.HTeamScore, prevPlay.VTeamScore, play.HTeamScore, play.VTeamScore)
+	var eventType EventType
+	switch play.EventMsgType {
+	case 6:
+		eventType = Penalty
+	case 8:
+		eventType = Substitution
+	case 9:
+		eventType = Timeout
+	case 12:
+		eventType = PeriodStart
+	case 13:
+		eventType = PeriodEnd
+	case 18: // Instant Replay
+		eventType = Challenge
+	}
+	if eventType != "" {
+		events = append(events, buildEvent(eventType, playResult, period, play.HTeamScore, play.VTeamScore))
+	}
+	if play.EventMsgType == 13 && nbaGameOver(period, play) {
+		events = append(events, buildEvent(GameFinal, playResult, period, play.HTeamScore, play.VTeamScore))
+	}
+	return events
+}
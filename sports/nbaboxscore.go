@@ -0,0 +1,274 @@
+package sports
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const nbaBaseUrl = "https://data.nba.net/prod/"
+const nbaBoxScoreEndpoint = "v1/%s/%s_boxscore.json"
+const nbaStatsBoxScoreEndpoint = "boxscoretraditionalv2/?GameID=%s&StartPeriod=0&EndPeriod=0&StartRange=0&EndRange=0&RangeType=0"
+const nbaStatsBoxScoreSummaryEndpoint = "boxscoresummaryv2/?GameID=%s"
+
+// nbaStatLine is a player's or team's stats, shared by nba and nbaStats
+type nbaStatLine struct {
+	minutes                string
+	points                 int
+	offensiveRebounds      int
+	defensiveRebounds      int
+	assists                int
+	steals                 int
+	blocks                 int
+	turnovers              int
+	fouls                  int
+	fieldGoalsMade         int
+	fieldGoalsAttempted    int
+	threePointersMade      int
+	threePointersAttempted int
+	freeThrowsMade         int
+	freeThrowsAttempted    int
+	plusMinus              int
+}
+
+type nbaBoxScorePlayer struct {
+	id       string
+	name     string
+	number   string
+	position string
+	stats    nbaStatLine
+}
+
+type nbaBoxScoreTeam struct {
+	teamId  string
+	abbr    string
+	totals  nbaStatLine
+	players []nbaBoxScorePlayer
+}
+
+// data.nba.net stat lines, every number is a string
+type nbaDataStatLine struct {
+	Min       string `json:"min"`
+	Points    string `json:"points"`
+	OffReb    string `json:"offReb"`
+	DefReb    string `json:"defReb"`
+	Assists   string `json:"assists"`
+	Steals    string `json:"steals"`
+	Blocks    string `json:"blocks"`
+	Turnovers string `json:"turnovers"`
+	PFouls    string `json:"pFouls"`
+	Fgm       string `json:"fgm"`
+	Fga       string `json:"fga"`
+	Tpm       string `json:"tpm"`
+	Tpa       string `json:"tpa"`
+	Ftm       string `json:"ftm"`
+	Fta       string `json:"fta"`
+	PlusMinus string `json:"plusMinus"`
+}
+
+type nbaDataBoxScoreTeam struct {
+	TeamId  string `json:"teamId"`
+	TriCode string `json:"triCode"`
+}
+
+type nbaDataBoxScore struct {
+	BasicGameData struct {
+		StatusNum int                 `json:"statusNum"`
+		HTeam     nbaDataBoxScoreTeam `json:"hTeam"`
+		VTeam     nbaDataBoxScoreTeam `json:"vTeam"`
+	} `json:"basicGameData"`
+	Stats struct {
+		HTeam struct {
+			Totals nbaDataStatLine `json:"totals"`
+		} `json:"hTeam"`
+		VTeam struct {
+			Totals nbaDataStatLine `json:"totals"`
+		} `json:"vTeam"`
+		ActivePlayers []struct {
+			nbaDataStatLine
+			PersonId  string `json:"personId"`
+			FirstName string `json:"firstName"`
+			LastName  string `json:"lastName"`
+			Jersey    string `json:"jersey"`
+			TeamId    string `json:"teamId"`
+			Pos       string `json:"pos"`
+		} `json:"activePlayers"`
+	} `json:"stats"`
+}
+
+func (n *nba) BoxScore(params url.Values) map[string]interface{} {
+	gameId := params.Get("gameId")
+	home, away, state, status := n.fetchBoxScore(gameId, n.gameDate(gameId, params.Get("gameDate")))
+	return setUpstreamStatus(buildResultFromNBABoxScore(&home, &away, state), status)
+}
+
+// fetchBoxScore requests the box score of the game with gameId, played on date (US Eastern)
+func (n *nba) fetchBoxScore(gameId string, date time.Time) (nbaBoxScoreTeam, nbaBoxScoreTeam, ScheduleState, int) {
+	boxScore := nbaDataBoxScore{}
+	status := fetchJSON(nbaBaseUrl+fmt.Sprintf(nbaBoxScoreEndpoint, date.Format("20060102"), gameId), nil, &boxScore)
+	home := nbaBoxScoreTeam{
+		teamId: boxScore.BasicGameData.HTeam.TeamId,
+		abbr:   boxScore.BasicGameData.HTeam.TriCode,
+		totals: boxScore.Stats.HTeam.Totals.statLine(),
+	}
+	away := nbaBoxScoreTeam{
+		teamId: boxScore.BasicGameData.VTeam.TeamId,
+		abbr:   boxScore.BasicGameData.VTeam.TriCode,
+		totals: boxScore.Stats.VTeam.Totals.statLine(),
+	}
+	for _, player := range boxScore.Stats.ActivePlayers {
+		boxScorePlayer := nbaBoxScorePlayer{
+			id:       player.PersonId,
+			name:     strings.TrimSpace(player.FirstName + " " + player.LastName),
+			number:   player.Jersey,
+			position: player.Pos,
+			stats:    player.statLine(),
+		}
+		if player.TeamId == home.teamId {
+			home.players = append(home.players, boxScorePlayer)
+		} else {
+			away.players = append(away.players, boxScorePlayer)
+		}
+	}
+	return home, away, n.ParseScheduleState(boxScore.BasicGameData.StatusNum), status
+}
+
+func (s nbaDataStatLine) statLine() nbaStatLine {
+	return nbaStatLine{
+		minutes:                s.Min,
+		points:                 statsInt(s.Points),
+		offensiveRebounds:      statsInt(s.OffReb),
+		defensiveRebounds:      statsInt(s.DefReb),
+		assists:                statsInt(s.Assists),
+		steals:                 statsInt(s.Steals),
+		blocks:                 statsInt(s.Blocks),
+		turnovers:              statsInt(s.Turnovers),
+		fouls:                  statsInt(s.PFouls),
+		fieldGoalsMade:         statsInt(s.Fgm),
+		fieldGoalsAttempted:    statsInt(s.Fga),
+		threePointersMade:      statsInt(s.Tpm),
+		threePointersAttempted: statsInt(s.Tpa),
+		freeThrowsMade:         statsInt(s.Ftm),
+		freeThrowsAttempted:    statsInt(s.Fta),
+		plusMinus:              statsInt(s.PlusMinus),
+	}
+}
+
+func (n *nbaStats) BoxScore(params url.Values) map[string]interface{} {
+	home, away, state, status := n.fetchBoxScore(params.Get("gameId"))
+	return setUpstreamStatus(buildResultFromNBABoxScore(&home, &away, state), status)
+}
+
+// fetchBoxScore requests the box score of the game with gameId, and its summary for the home and away teams
+func (n *nbaStats) fetchBoxScore(gameId string) (nbaBoxScoreTeam, nbaBoxScoreTeam, ScheduleState, int) {
+	home, away := nbaBoxScoreTeam{}, nbaBoxScoreTeam{}
+	summary := nbaStatsResponse{}
+	status := fetchJSON(nbaStatsBaseUrl+fmt.Sprintf(nbaStatsBoxScoreSummaryEndpoint, gameId), nbaStatsHeaders, &summary)
+	if status != http.StatusOK {
+		return home, away, Preview, status
+	}
+	response := nbaStatsResponse{}
+	status = fetchJSON(nbaStatsBaseUrl+fmt.Sprintf(nbaStatsBoxScoreEndpoint, gameId), nbaStatsHeaders, &response)
+	state := Preview
+	for _, row := range summary.rows("GameSummary") {
+		home.teamId = statsString(row["HOME_TEAM_ID"])
+		away.teamId = statsString(row["VISITOR_TEAM_ID"])
+		state = n.ParseScheduleState(statsInt(row["GAME_STATUS_ID"]))
+	}
+	for _, row := range response.rows("TeamStats") {
+		team := &away
+		if statsString(row["TEAM_ID"]) == home.teamId {
+			team = &home
+		}
+		team.abbr = statsString(row["TEAM_ABBREVIATION"])
+		team.totals = nbaStatLineFromStats(row)
+	}
+	for _, row := range response.rows("PlayerStats") {
+		player := nbaBoxScorePlayer{
+			id:       statsString(row["PLAYER_ID"]),
+			name:     statsString(row["PLAYER_NAME"]),
+			position: statsString(row["START_POSITION"]),
+			stats:    nbaStatLineFromStats(row),
+		}
+		if statsString(row["TEAM_ID"]) == home.teamId {
+			home.players = append(home.players, player)
+		} else {
+			away.players = append(away.players, player)
+		}
+	}
+	return home, away, state, status
+}
+
+func nbaStatLineFromStats(row map[string]interface{}) nbaStatLine {
+	return nbaStatLine{
+		minutes:                statsString(row["MIN"]),
+		points:                 statsInt(row["PTS"]),
+		offensiveRebounds:      statsInt(row["OREB"]),
+		defensiveRebounds:      statsInt(row["DREB"]),
+		assists:                statsInt(row["AST"]),
+		steals:                 statsInt(row["STL"]),
+		blocks:                 statsInt(row["BLK"]),
+		turnovers:              statsInt(row["TO"]),
+		fouls:                  statsInt(row["PF"]),
+		fieldGoalsMade:         statsInt(row["FGM"]),
+		fieldGoalsAttempted:    statsInt(row["FGA"]),
+		threePointersMade:      statsInt(row["FG3M"]),
+		threePointersAttempted: statsInt(row["FG3A"]),
+		freeThrowsMade:         statsInt(row["FTM"]),
+		freeThrowsAttempted:    statsInt(row["FTA"]),
+		plusMinus:              statsInt(row["PLUS_MINUS"]),
+	}
+}
+
+func buildResultFromNBABoxScore(home *nbaBoxScoreTeam, away *nbaBoxScoreTeam, state ScheduleState) map[string]interface{} {
+	result := make(map[string]interface{})
+	result["home"] = buildTeamFromNBABoxScore(home)
+	result["away"] = buildTeamFromNBABoxScore(away)
+	result["metadata"] = map[string]interface{}{"state": state}
+	return result
+}
+
+func buildTeamFromNBABoxScore(team *nbaBoxScoreTeam) map[string]interface{} {
+	result := make(map[string]interface{})
+	result["teamId"] = team.teamId
+	result["abbr"] = team.abbr
+	stats := team.totals.toMap()
+	delete(stats, "plusMinus")
+	result["stats"] = stats
+	players := make([]map[string]interface{}, len(team.players))
+	for i, player := range team.players {
+		players[i] = map[string]interface{}{
+			"id":       player.id,
+			"name":     player.name,
+			"number":   player.number,
+			"position": player.position,
+			"stats":    player.stats.toMap(),
+		}
+	}
+	result["players"] = players
+	return result
+}
+
+func (s *nbaStatLine) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"minutes":                s.minutes,
+		"points":                 s.points,
+		"rebounds":               s.offensiveRebounds + s.defensiveRebounds,
+		"offensiveRebounds":      s.offensiveRebounds,
+		"defensiveRebounds":      s.defensiveRebounds,
+		"assists":                s.assists,
+		"steals":                 s.steals,
+		"blocks":                 s.blocks,
+		"turnovers":              s.turnovers,
+		"fouls":                  s.fouls,
+		"fieldGoalsMade":         s.fieldGoalsMade,
+		"fieldGoalsAttempted":    s.fieldGoalsAttempted,
+		"threePointersMade":      s.threePointersMade,
+		"threePointersAttempted": s.threePointersAttempted,
+		"freeThrowsMade":         s.freeThrowsMade,
+		"freeThrowsAttempted":    s.freeThrowsAttempted,
+		"plusMinus":              s.plusMinus,
+	}
+}
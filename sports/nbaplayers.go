@@ -0,0 +1,131 @@
+package sports
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const nbaPlayerStatsEndpoint = "v1/%d/players/%s_profile.json"
+const nbaStatsPlayerEndpoint = "commonplayerinfo/?LeagueID=00&PlayerID=%s"
+const nbaStatsPlayerStatsEndpoint = "playercareerstats/?LeagueID=00&PerMode=PerGame&PlayerID=%s"
+const nbaHeadshotUrl = "https://ak-static.cms.nba.com/wp-content/uploads/headshots/nba/latest/260x190/%s.png"
+
+type nbaDataPlayerProfile struct {
+	League struct {
+		Standard struct {
+			Stats struct {
+				CareerSummary map[string]interface{} `json:"careerSummary"`
+				RegularSeason struct {
+					Season []struct {
+						SeasonYear int                    `json:"seasonYear"`
+						Total      map[string]interface{} `json:"total"`
+					} `json:"season"`
+				} `json:"regularSeason"`
+			} `json:"stats"`
+		} `json:"standard"`
+	} `json:"league"`
+}
+
+func (n *nba) Player(params url.Values) map[string]interface{} {
+	playerId := params.Get("playerId")
+	players := nbaDataPlayers{}
+	status := fetchJSON(nbaBaseUrl+fmt.Sprintf(nbaPlayersEndpoint, nbaSeasonStartYear(time.Now().In(easternLocation()))), nil, &players)
+	result := make(map[string]interface{})
+	for _, player := range players.League.Standard {
+		if player.PersonId != playerId {
+			continue
+		}
+		result["content"] = map[string]interface{}{
+			"playerId":  player.PersonId,
+			"name":      strings.TrimSpace(player.FirstName + " " + player.LastName),
+			"firstName": player.FirstName,
+			"lastName":  player.LastName,
+			"number":    player.Jersey,
+			"position":  player.Pos,
+			"team":      TeamId(n.Name(), player.TeamId),
+			"birthDate": strings.TrimSuffix(player.DateOfBirthUTC, "T00:00:00.000Z"),
+			"country":   player.Country,
+			"college":   player.CollegeName,
+			"height":    fmt.Sprintf("%s-%s", player.HeightFeet, player.HeightInches),
+			"weight":    statsInt(player.WeightPounds),
+			"yearsPro":  statsInt(player.YearsPro),
+			"active":    player.IsActive,
+			"headshot":  fmt.Sprintf(nbaHeadshotUrl, player.PersonId),
+		}
+	}
+	if status == http.StatusOK && result["content"] == nil {
+		status = http.StatusNotFound
+	}
+	return setUpstreamStatus(result, status)
+}
+
+func (n *nba) PlayerStats(params url.Values) map[string]interface{} {
+	profile := nbaDataPlayerProfile{}
+	status := fetchJSON(nbaBaseUrl+fmt.Sprintf(nbaPlayerStatsEndpoint, nbaSeasonStartYear(time.Now().In(easternLocation())), url.PathEscape(params.Get("playerId"))), nil, &profile)
+	stats := profile.League.Standard.Stats
+	seasons := make([]map[string]interface{}, len(stats.RegularSeason.Season))
+	for i, season := range stats.RegularSeason.Season {
+		year := season.SeasonYear
+		seasons[i] = map[string]interface{}{
+			"season": fmt.Sprintf("%d-%02d", year, (year+1)%100),
+			"stats":  season.Total,
+		}
+	}
+	career := stats.CareerSummary
+	if career == nil {
+		career = make(map[string]interface{})
+	}
+	return setUpstreamStatus(map[string]interface{}{"content": map[string]interface{}{"seasons": seasons, "career": career}}, status)
+}
+
+func (n *nbaStats) Player(params url.Values) map[string]interface{} {
+	response := nbaStatsResponse{}
+	status := fetchJSON(nbaStatsBaseUrl+fmt.Sprintf(nbaStatsPlayerEndpoint, url.QueryEscape(params.Get("playerId"))), nbaStatsHeaders, &response)
+	result := make(map[string]interface{})
+	for _, row := range response.rows("CommonPlayerInfo") {
+		playerId := statsString(row["PERSON_ID"])
+		result["content"] = map[string]interface{}{
+			"playerId":  playerId,
+			"name":      statsString(row["DISPLAY_FIRST_LAST"]),
+			"firstName": statsString(row["FIRST_NAME"]),
+			"lastName":  statsString(row["LAST_NAME"]),
+			"number":    statsString(row["JERSEY"]),
+			"position":  statsString(row["POSITION"]),
+			"team":      TeamId(n.Name(), statsString(row["TEAM_ID"])),
+			"birthDate": strings.TrimSuffix(statsString(row["BIRTHDATE"]), "T00:00:00"),
+			"country":   statsString(row["COUNTRY"]),
+			"college":   statsString(row["SCHOOL"]),
+			"height":    statsString(row["HEIGHT"]),
+			"weight":    statsInt(row["WEIGHT"]),
+			"yearsPro":  statsInt(row["SEASON_EXP"]),
+			"active":    statsString(row["ROSTERSTATUS"]) == "Active",
+			"headshot":  fmt.Sprintf(nbaHeadshotUrl, playerId),
+		}
+	}
+	if status == http.StatusOK && result["content"] == nil {
+		status = http.StatusNotFound
+	}
+	return setUpstreamStatus(result, status)
+}
+
+func (n *nbaStats) PlayerStats(params url.Values) map[string]interface{} {
+	response := nbaStatsResponse{}
+	status := fetchJSON(nbaStatsBaseUrl+fmt.Sprintf(nbaStatsPlayerStatsEndpoint, url.QueryEscape(params.Get("playerId"))), nbaStatsHeaders, &response)
+	rows := response.rows("SeasonTotalsRegularSeason")
+	seasons := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		seasons[i] = map[string]interface{}{
+			"season": statsString(row["SEASON_ID"]),
+			"team":   TeamId(n.Name(), statsString(row["TEAM_ID"])),
+			"stats":  row,
+		}
+	}
+	career := make(map[string]interface{})
+	for _, row := range response.rows("CareerTotalsRegularSeason") {
+		career = row
+	}
+	return setUpstreamStatus(map[string]interface{}{"content": map[string]interface{}{"seasons": seasons, "career": career}}, status)
+}
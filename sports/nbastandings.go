@@ -0,0 +1,99 @@
+package sports
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const nbaStandingsEndpoint = "v1/current/standings_division.json"
+const nbaStatsStandingsEndpoint = "leaguestandingsv3/?LeagueID=00&Season=%s&SeasonType=Regular+Season"
+
+type nbaDataStandings struct {
+	League struct {
+		Standard struct {
+			Conference map[string]map[string][]struct { // Conference (east, west) and division (atlantic, ...) names
+				TeamId        string `json:"teamId"`
+				Win           string `json:"win"`
+				Loss          string `json:"loss"`
+				LastTenWin    string `json:"lastTenWin"`
+				LastTenLoss   string `json:"lastTenLoss"`
+				Streak        string `json:"streak"`
+				IsWinStreak   bool   `json:"isWinStreak"`
+				TeamSitesOnly struct {
+					TeamName     string `json:"teamName"`
+					TeamNickname string `json:"teamNickname"`
+					TeamTricode  string `json:"teamTricode"`
+				} `json:"teamSitesOnly"`
+			} `json:"conference"`
+		} `json:"standard"`
+	} `json:"league"`
+}
+
+func (n *nba) Standings(params url.Values) map[string]interface{} {
+	standings := nbaDataStandings{}
+	status := fetchJSON(nbaBaseUrl+nbaStandingsEndpoint, nil, &standings)
+	var teams []standingsTeam
+	for conference, divisions := range standings.League.Standard.Conference {
+		for division, divisionTeams := range divisions {
+			for _, divisionTeam := range divisionTeams {
+				streak := "L" + divisionTeam.Streak
+				if divisionTeam.IsWinStreak {
+					streak = "W" + divisionTeam.Streak
+				}
+				team := standingsTeam{
+					teamId:     divisionTeam.TeamId,
+					name:       fmt.Sprintf("%s %s", divisionTeam.TeamSitesOnly.TeamName, divisionTeam.TeamSitesOnly.TeamNickname),
+					abbr:       divisionTeam.TeamSitesOnly.TeamTricode,
+					conference: strings.Title(conference),
+					division:   strings.Title(division),
+					wins:       statsInt(divisionTeam.Win),
+					losses:     statsInt(divisionTeam.Loss),
+					streak:     streak,
+					lastTen:    fmt.Sprintf("%s-%s", divisionTeam.LastTenWin, divisionTeam.LastTenLoss),
+				}
+				team.gamesPlayed = team.wins + team.losses
+				teams = append(teams, team)
+			}
+		}
+	}
+	return setUpstreamStatus(buildResultFromStandings(teams, false), status)
+}
+
+func (n *nbaStats) Standings(params url.Values) map[string]interface{} {
+	response := nbaStatsResponse{}
+	status := fetchJSON(nbaStatsBaseUrl+fmt.Sprintf(nbaStatsStandingsEndpoint, nbaSeason(time.Now().In(easternLocation()))), nbaStatsHeaders, &response)
+	var teams []standingsTeam
+	for _, row := range response.rows("Standings") {
+		team := standingsTeam{
+			teamId:     statsString(row["TeamID"]),
+			name:       fmt.Sprintf("%s %s", statsString(row["TeamCity"]), statsString(row["TeamName"])),
+			abbr:       statsString(row["TeamAbbreviation"]),
+			conference: statsString(row["Conference"]),
+			division:   statsString(row["Division"]),
+			wins:       statsInt(row["WINS"]),
+			losses:     statsInt(row["LOSSES"]),
+			streak:     strings.Replace(statsString(row["strCurrentStreak"]), " ", "", 1),
+			lastTen:    strings.TrimSpace(statsString(row["L10"])),
+		}
+		team.gamesPlayed = team.wins + team.losses
+		teams = append(teams, team)
+	}
+	return setUpstreamStatus(buildResultFromStandings(teams, false), status)
+}
+
+// nbaSeason returns the season (2019-20) that date is in
+func nbaSeason(date time.Time) string {
+	year := nbaSeasonStartYear(date)
+	return fmt.Sprintf("%d-%02d", year, (year+1)%100)
+}
+
+// nbaSeasonStartYear returns the year the season that date is in started, seasons start in October
+func nbaSeasonStartYear(date time.Time) int {
+	year := date.Year()
+	if date.Month() < time.October {
+		year--
+	}
+	return year
+}
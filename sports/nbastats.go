@@ -0,0 +1,217 @@
+package sports
+
+import (
+	"fmt"
+	"github.com/henrymxu/gonba"
+	"github.com/ngaut/log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const nbaStatsBaseUrl = "https://stats.nba.com/stats/"
+const nbaStatsScheduleEndpoint = "scoreboardv2/?GameDate=%s&LeagueID=00&DayOffset=0"
+const nbaStatsPlayByPlayEndpoint = "playbyplayv2/?GameID=%s&StartPeriod=%d&EndPeriod=%d"
+
+// stats.nba.com rejects requests that do not look like they come from nba.com
+var nbaStatsHeaders = map[string]string{
+	"User-Agent":         "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/78.0 Safari/537.36",
+	"Referer":            "https://stats.nba.com/",
+	"Origin":             "https://stats.nba.com",
+	"x-nba-stats-origin": "stats",
+	"x-nba-stats-token":  "true",
+}
+
+// nbaStats is an alternative NBA provider backed by stats.nba.com, it produces the same results as nba
+type nbaStats struct {
+	nba
+}
+
+type nbaStatsResultSet struct {
+	Name    string          `json:"name"`
+	Headers []string        `json:"headers"`
+	RowSet  [][]interface{} `json:"rowSet"`
+}
+
+type nbaStatsResponse struct {
+	ResultSets []nbaStatsResultSet `json:"resultSets"`
+}
+
+func InitNBAStats() *nbaStats {
+	return &nbaStats{
+		nba: nba{
+			rosters: make(map[string]nbaRoster),
+		},
+	}
+}
+
+func (n *nbaStats) Provider() string {
+	return "stats.nba.com"
+}
+
+func (n *nbaStats) Schedule(params url.Values) map[string]interface{} {
+	date := time.Now()
+	if params != nil && params.Get("date") != "" {
+		tempDate, err := CreateDateFromString(params.Get("date"))
+		if err != nil {
+			log.Errorf("NBA stats schedule date error: %v", err)
+		} else {
+			date = tempDate
+		}
+	}
+	response := nbaStatsResponse{}
+	status := fetchJSON(nbaStatsBaseUrl+fmt.Sprintf(nbaStatsScheduleEndpoint, date.Format(dateLayout)), nbaStatsHeaders, &response)
+	schedule := buildNBAScheduleFromStats(&response)
+	return setUpstreamStatus(buildResultFromNBASchedule(&schedule), status)
+}
+
+// PlayByPlay returns the plays after the cursor in params["date"], using the same cursor as nba so providers can fail over
+func (n *nbaStats) PlayByPlay(params url.Values) map[string]interface{} {
+	gameId := params.Get("gameId")
+	result := buildResultFromNBAPlayByPlay(parseNBACursor(params.Get("date")), func(period int) ([]nbaPlay, int) {
+		response := nbaStatsResponse{}
+		status := fetchJSON(nbaStatsBaseUrl+fmt.Sprintf(nbaStatsPlayByPlayEndpoint, gameId, 1, period), nbaStatsHeaders, &response)
+		return buildNBAPlaysFromStats(&response, period), status
+	})
+	return n.resolveParticipants(gameId, result, func() (nbaBoxScoreTeam, nbaBoxScoreTeam, int) {
+		home, away, _, status := n.fetchBoxScore(gameId)
+		return home, away, status
+	})
+}
+
+// rows returns the rows of the result set with name, keyed by header
+func (r *nbaStatsResponse) rows(name string) []map[string]interface{} {
+	for _, resultSet := range r.ResultSets {
+		if resultSet.Name != name {
+			continue
+		}
+		rows := make([]map[string]interface{}, len(resultSet.RowSet))
+		for i, rowSet := range resultSet.RowSet {
+			row := make(map[string]interface{})
+			for j, header := range resultSet.Headers {
+				if j < len(rowSet) {
+					row[header] = rowSet[j]
+				}
+			}
+			rows[i] = row
+		}
+		return rows
+	}
+	return nil
+}
+
+func buildNBAScheduleFromStats(response *nbaStatsResponse) gonba.Schedule {
+	teams := make(map[string]gonba.Team) // Key is GAME_ID + TEAM_ID
+	for _, row := range response.rows("LineScore") {
+		team := gonba.Team{}
+		team.Id = statsString(row["TEAM_ID"])
+		team.City = statsString(row["TEAM_CITY_NAME"])
+		team.Name = statsString(row["TEAM_NAME"])
+		team.Abbr = statsString(row["TEAM_ABBREVIATION"])
+		record := strings.Split(statsString(row["TEAM_WINS_LOSSES"]), "-")
+		if len(record) == 2 {
+			team.Wins, _ = strconv.Atoi(record[0])
+			team.Losses, _ = strconv.Atoi(record[1])
+		}
+		team.Score = statsInt(row["PTS"])
+		teams[statsString(row["GAME_ID"])+team.Id] = team
+	}
+	schedule := gonba.Schedule{}
+	for _, row := range response.rows("GameHeader") {
+		game := gonba.Game{}
+		game.GameId = statsString(row["GAME_ID"])
+		game.GameDate = parseNBAStatsGameDate(statsString(row["GAME_DATE_EST"]), statsString(row["GAME_STATUS_TEXT"]))
+		game.Status.StatusCode = statsInt(row["GAME_STATUS_ID"])
+		game.Status.StatusString = statsString(row["GAME_STATUS_TEXT"])
+		game.Quarter = statsInt(row["LIVE_PERIOD"])
+		game.QuarterTime = strings.TrimSpace(statsString(row["LIVE_PC_TIME"]))
+		game.Venue = statsString(row["ARENA_NAME"])
+		game.Teams.Home = teams[game.GameId+statsString(row["HOME_TEAM_ID"])]
+		game.Teams.Away = teams[game.GameId+statsString(row["VISITOR_TEAM_ID"])]
+		schedule.Games = append(schedule.Games, game)
+	}
+	return schedule
+}
+
+// parseNBAStatsGameDate combines the date of a game (2019-10-31T00:00:00) with its status text (7:30 pm ET) if it has not started
+func parseNBAStatsGameDate(date string, status string) time.Time {
+	eastern := easternLocation()
+	day, err := time.ParseInLocation("2006-01-02T15:04:05", date, eastern)
+	if err != nil {
+		return time.Time{}
+	}
+	startTime, err := time.Parse("3:04 pm", strings.TrimSuffix(strings.TrimSpace(status), " ET"))
+	if err != nil {
+		return day
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), startTime.Hour(), startTime.Minute(), 0, 0, eastern)
+}
+
+// buildNBAPlaysFromStats converts the rows of period, earlier periods are only used to carry over the score
+func buildNBAPlaysFromStats(response *nbaStatsResponse, period int) []nbaPlay {
+	plays := make([]nbaPlay, 0)
+	homeScore, awayScore := 0, 0
+	for _, row := range response.rows("PlayByPlay") {
+		if score := strings.Split(statsString(row["SCORE"]), " - "); len(score) == 2 { // Only present when the score changes, away - home
+			awayScore, _ = strconv.Atoi(score[0])
+			homeScore, _ = strconv.Atoi(score[1])
+		}
+		if statsInt(row["PERIOD"]) != period {
+			continue
+		}
+		play := nbaPlay{}
+		play.Clock = statsString(row["PCTIMESTRING"])
+		play.EventMsgType = statsInt(row["EVENTMSGTYPE"])
+		for _, key := range []string{"HOMEDESCRIPTION", "NEUTRALDESCRIPTION", "VISITORDESCRIPTION"} {
+			if description := statsString(row[key]); description != "" {
+				play.Formatted.Description = description
+				break
+			}
+		}
+		play.TeamID = statsString(row["PLAYER1_TEAM_ID"])
+		play.PersonID = statsString(row["PLAYER1_ID"])
+		play.HTeamScore = homeScore
+		play.VTeamScore = awayScore
+		roles := nbaParticipantRoles[play.EventMsgType]
+		for i, role := range roles {
+			prefix := fmt.Sprintf("PLAYER%d_", i+1)
+			playerId := statsString(row[prefix+"ID"])
+			if role == "" || playerId == "" || playerId == "0" {
+				continue
+			}
+			play.participants = append(play.participants, nbaParticipant{
+				role:     role,
+				playerId: playerId,
+				name:     statsString(row[prefix+"NAME"]),
+				teamId:   statsString(row[prefix+"TEAM_ID"]),
+				team:     statsString(row[prefix+"TEAM_ABBREVIATION"]),
+			})
+		}
+		plays = append(plays, play)
+	}
+	return plays
+}
+
+// statsString converts a stats.nba.com cell (string, number or null) to a string
+func statsString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// statsInt converts a stats.nba.com cell (string, number or null) to an int
+func statsInt(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case string:
+		i, _ := strconv.Atoi(v)
+		return i
+	}
+	return 0
+}
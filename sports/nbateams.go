@@ -0,0 +1,126 @@
+package sports
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const nbaTeamsEndpoint = "v2/%d/teams.json"
+const nbaPlayersEndpoint = "v1/%d/players.json"
+const nbaStatsRosterEndpoint = "commonteamroster/?LeagueID=00&Season=%s&TeamID=%s"
+
+type nbaDataTeams struct {
+	League struct {
+		Standard []struct {
+			IsNBAFranchise bool   `json:"isNBAFranchise"`
+			TeamId         string `json:"teamId"`
+			FullName       string `json:"fullName"`
+			Tricode        string `json:"tricode"`
+			City           string `json:"city"`
+			Nickname       string `json:"nickname"`
+			ConfName       string `json:"confName"`
+			DivName        string `json:"divName"`
+		} `json:"standard"`
+	} `json:"league"`
+}
+
+type nbaDataPlayers struct {
+	League struct {
+		Standard []struct {
+			PersonId       string `json:"personId"`
+			FirstName      string `json:"firstName"`
+			LastName       string `json:"lastName"`
+			TeamId         string `json:"teamId"`
+			Jersey         string `json:"jersey"`
+			Pos            string `json:"pos"`
+			IsActive       bool   `json:"isActive"`
+			HeightFeet     string `json:"heightFeet"`
+			HeightInches   string `json:"heightInches"`
+			WeightPounds   string `json:"weightPounds"`
+			DateOfBirthUTC string `json:"dateOfBirthUTC"`
+			Country        string `json:"country"`
+			CollegeName    string `json:"collegeName"`
+			YearsPro       string `json:"yearsPro"`
+		} `json:"standard"`
+	} `json:"league"`
+}
+
+func (n *nba) Teams(params url.Values) map[string]interface{} {
+	teams := nbaDataTeams{}
+	status := fetchJSON(nbaBaseUrl+fmt.Sprintf(nbaTeamsEndpoint, nbaSeasonStartYear(time.Now().In(easternLocation()))), nil, &teams)
+	content := make([]map[string]interface{}, 0, len(teams.League.Standard))
+	for _, team := range teams.League.Standard {
+		if !team.IsNBAFranchise { // All star and international teams
+			continue
+		}
+		content = append(content, map[string]interface{}{
+			"id":         TeamId(n.Name(), team.TeamId),
+			"teamId":     team.TeamId,
+			"name":       team.FullName,
+			"abbr":       team.Tricode,
+			"location":   team.City,
+			"nickname":   team.Nickname,
+			"conference": team.ConfName,
+			"division":   team.DivName,
+		})
+	}
+	return setUpstreamStatus(map[string]interface{}{"content": content}, status)
+}
+
+func (n *nba) Roster(params url.Values) map[string]interface{} {
+	teamId := params.Get("teamId")
+	players := nbaDataPlayers{}
+	status := fetchJSON(nbaBaseUrl+fmt.Sprintf(nbaPlayersEndpoint, nbaSeasonStartYear(time.Now().In(easternLocation()))), nil, &players)
+	content := make([]map[string]interface{}, 0)
+	for _, player := range players.League.Standard {
+		if player.TeamId != teamId || !player.IsActive {
+			continue
+		}
+		content = append(content, map[string]interface{}{
+			"playerId": player.PersonId,
+			"name":     strings.TrimSpace(player.FirstName + " " + player.LastName),
+			"number":   player.Jersey,
+			"position": player.Pos,
+		})
+	}
+	return setUpstreamStatus(map[string]interface{}{"content": content}, status)
+}
+
+// Teams are built from the standings, since stats.nba.com has no teams endpoint
+func (n *nbaStats) Teams(params url.Values) map[string]interface{} {
+	response := nbaStatsResponse{}
+	status := fetchJSON(nbaStatsBaseUrl+fmt.Sprintf(nbaStatsStandingsEndpoint, nbaSeason(time.Now().In(easternLocation()))), nbaStatsHeaders, &response)
+	rows := response.rows("Standings")
+	content := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		content[i] = map[string]interface{}{
+			"id":         TeamId(n.Name(), statsString(row["TeamID"])),
+			"teamId":     statsString(row["TeamID"]),
+			"name":       fmt.Sprintf("%s %s", statsString(row["TeamCity"]), statsString(row["TeamName"])),
+			"abbr":       statsString(row["TeamAbbreviation"]),
+			"location":   statsString(row["TeamCity"]),
+			"nickname":   statsString(row["TeamName"]),
+			"conference": statsString(row["Conference"]),
+			"division":   statsString(row["Division"]),
+		}
+	}
+	return setUpstreamStatus(map[string]interface{}{"content": content}, status)
+}
+
+func (n *nbaStats) Roster(params url.Values) map[string]interface{} {
+	response := nbaStatsResponse{}
+	status := fetchJSON(nbaStatsBaseUrl+fmt.Sprintf(nbaStatsRosterEndpoint, nbaSeason(time.Now().In(easternLocation())), url.QueryEscape(params.Get("teamId"))), nbaStatsHeaders, &response)
+	rows := response.rows("CommonTeamRoster")
+	content := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		content[i] = map[string]interface{}{
+			"playerId": statsString(row["PLAYER_ID"]),
+			"name":     statsString(row["PLAYER"]),
+			"number":   statsString(row["NUM"]),
+			"position": statsString(row["POSITION"]),
+		}
+	}
+	return setUpstreamStatus(map[string]interface{}{"content": content}, status)
+}
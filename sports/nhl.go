@@ -5,18 +5,23 @@ import (
 	"github.com/henrymxu/gonhl"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 const lastCheckTimeFormat = "2006-01-02 15:04:05"
 
 type nhl struct {
-	client *gonhl.Client
+	client         *gonhl.Client
+	liveGames      map[int]*nhlLiveGame // Games being followed with the diffPatch feed
+	liveGamesMutex sync.Mutex
 }
 
 func InitNHL() *nhl {
 	return &nhl{
-		client: gonhl.NewClient(),
+		client:    gonhl.NewClient(),
+		liveGames: make(map[int]*nhlLiveGame),
 	}
 }
 
@@ -24,22 +29,31 @@ func (n *nhl) Name() string {
 	return "nhl"
 }
 
+func (n *nhl) Provider() string {
+	return "statsapi.web.nhl.com"
+}
+
 func (n *nhl) Schedule(params url.Values) map[string]interface{} {
-	schedule, _ := n.client.GetSchedule(buildScheduleParamsFromParams(params))
-	return buildResultFromNHLSchedule(&schedule)
+	schedule, status := n.client.GetSchedule(buildScheduleParamsFromParams(params))
+	return setUpstreamStatus(buildResultFromNHLSchedule(&schedule), status)
 }
 
 func (n *nhl) PlayByPlay(params url.Values) map[string]interface{} {
 	id, _ := strconv.Atoi(params.Get("gameId"))
-	liveData, _ := n.client.GetGameLiveData(id)
+	liveData, status := n.getGameLiveData(id)
 	lastCheckString := params.Get("date")
 	result := buildResultFromLiveData(&liveData, lastCheckString)
-	return result
+	if buildStateFromLiveData(&liveData) == Complete {
+		n.removeLiveGame(id)
+	}
+	return setUpstreamStatus(result, status)
 }
 
 func (n *nhl) ParseScheduleState(statusCode int) ScheduleState {
 	status := Preview
-	if statusCode >= 5 { // 5 6 7 states
+	if statusCode == 9 {
+		status = Postponed
+	} else if statusCode >= 5 { // 5 6 7 states
 		status = Complete
 	} else if statusCode > 2 { // 3 4 states
 		status = Live
@@ -70,7 +84,8 @@ func buildResultFromLiveData(liveData *gonhl.LiveData, lastCheckString string) m
 	lastCheck, _ := time.Parse(lastCheckTimeFormat, lastCheckString)
 	result := make(map[string]interface{})
 	result["game"] = buildGameFromLiveData(liveData)
-	result["plays"] = buildPlaysFromPlays(&liveData.Plays, &lastCheck)
+	result["plays"] = buildPlaysFromPlays(&liveData.Plays, &lastCheck, &liveData.Boxscore)
+	result["events"] = buildEventsFromPlays(&liveData.Plays, &lastCheck, &liveData.Boxscore)
 	result["players"] = buildPlayersFromBoxScore(&liveData.Boxscore)
 	metadata := make(map[string]interface{})
 	metadata["state"] = buildStateFromLiveData(liveData)
@@ -112,22 +127,103 @@ func buildTeamFromLinescore(linescoreTeam *gonhl.LinescoreTeam) map[string]inter
 	return team
 }
 
-func buildPlaysFromPlays(playsData *gonhl.Plays, lastCheck *time.Time) []map[string]interface{} {
+func buildPlaysFromPlays(playsData *gonhl.Plays, lastCheck *time.Time, boxscore *gonhl.Boxscore) []map[string]interface{} {
 	plays := make([]map[string]interface{}, 0, len(playsData.AllPlays))
 	for _, playData := range playsData.AllPlays {
 		if lastCheck == nil || playData.About.DateTime.Sub(*lastCheck) > 0 {
 			play := make(map[string]interface{})
 			play["description"] = playData.Result.Description
 			play["typeId"] = playData.Result.EventTypeID
+			play["teamId"] = playData.Team.ID
 			play["periodTime"] = playData.About.PeriodTime
 			play["coordinates"] = map[string]float64{"x": playData.Coordinates.X, "y": playData.Coordinates.Y}
 			play["dateTime"] = playData.About.DateTime.Format(lastCheckTimeFormat)
+			play["participants"] = buildParticipantsFromPlay(&playData, boxscore)
 			plays = append(plays, play)
 		}
 	}
 	return plays
 }
 
+// buildEventsFromPlays maps the plays after lastCheck to their typed events
+func buildEventsFromPlays(playsData *gonhl.Plays, lastCheck *time.Time, boxscore *gonhl.Boxscore) []map[string]interface{} {
+	events := make([]map[string]interface{}, 0)
+	prevHome, prevAway := 0, 0
+	for _, playData := range playsData.AllPlays {
+		home, away := playData.About.Goals.Home, playData.About.Goals.Away
+		if lastCheck == nil || playData.About.DateTime.Sub(*lastCheck) > 0 {
+			play := map[string]interface{}{
+				"description":  playData.Result.Description,
+				"periodTime":   playData.About.PeriodTime,
+				"teamId":       playData.Team.ID,
+				"participants": buildParticipantsFromPlay(&playData, boxscore),
+			}
+			period := playData.About.Period
+			if playData.Result.EventTypeID == "GOAL" {
+				events = append(events, buildScoreEvents(play, period, prevHome, prevAway, home, away)...)
+			}
+			var eventType EventType
+			switch playData.Result.EventTypeID {
+			case "PENALTY":
+				eventType = Penalty
+			case "PERIOD_START":
+				eventType = PeriodStart
+			case "PERIOD_END":
+				eventType = PeriodEnd
+			case "CHALLENGE":
+				eventType = Challenge
+			case "GAME_END":
+				eventType = GameFinal
+			case "STOP":
+				if strings.Contains(playData.Result.Description, "Timeout") { // Team timeouts, not TV timeouts
+					eventType = Timeout
+				}
+			}
+			if eventType != "" {
+				events = append(events, buildEvent(eventType, play, period, home, away))
+			}
+		}
+		prevHome, prevAway = home, away
+	}
+	return events
+}
+
+// nhlParticipantRoles maps the player types of plays to participant roles
+var nhlParticipantRoles = map[string]ParticipantRole{
+	"Scorer":    Scorer,
+	"Assist":    Assister,
+	"Goalie":    Goalie,
+	"Shooter":   Shooter,
+	"Blocker":   Blocker,
+	"Hitter":    Hitter,
+	"Hittee":    Hittee,
+	"Winner":    Winner,
+	"Loser":     Loser,
+	"PenaltyOn": Penalized,
+	"DrewBy":    DrewPenalty,
+	"ServedBy":  ServedBy,
+}
+
+// buildParticipantsFromPlay resolves the players of a play with the game's box score, which has every dressed player
+func buildParticipantsFromPlay(playData *gonhl.Play, boxscore *gonhl.Boxscore) []map[string]interface{} {
+	participants := make([]map[string]interface{}, 0, len(playData.Players))
+	for _, playPlayer := range playData.Players {
+		role, ok := nhlParticipantRoles[playPlayer.PlayerType]
+		if !ok {
+			role = Player
+		}
+		number, team, teamId := "", "", 0
+		for _, boxscoreTeam := range []*gonhl.BoxscoreTeam{&boxscore.Teams.Home, &boxscore.Teams.Away} {
+			if boxscorePlayer, ok := boxscoreTeam.Players[fmt.Sprintf("ID%d", playPlayer.Player.ID)]; ok {
+				number, team, teamId = boxscorePlayer.JerseyNumber, boxscoreTeam.Team.TriCode, boxscoreTeam.Team.ID
+				break
+			}
+		}
+		participants = append(participants, buildParticipant(role, playPlayer.Player.ID, playPlayer.Player.FullName, number, team, teamId))
+	}
+	return participants
+}
+
 func buildPlayersFromBoxScore(boxscore *gonhl.Boxscore) map[string]interface{} {
 	players := make(map[string]interface{}, 0)
 	players["home"] = buildPlayersFromTeam(boxscore.Teams.Home)
@@ -179,6 +275,7 @@ func buildResultFromNHLSchedule(schedule *gonhl.Schedule) map[string]interface{}
 
 func parseNHLTeam(team gonhl.GameTeam) map[string]interface{} {
 	resultTeam := make(map[string]interface{})
+	resultTeam["id"] = TeamId("nhl", team.Team.ID)
 	resultTeam["teamId"] = team.Team.ID
 	resultTeam["name"] = team.Team.Name
 	resultTeam["abbr"] = team.Team.ShortName
@@ -0,0 +1,157 @@
+package sports
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+type nhlBoxScore struct {
+	Teams struct {
+		Home nhlBoxScoreTeam `json:"home"`
+		Away nhlBoxScoreTeam `json:"away"`
+	} `json:"teams"`
+}
+
+type nhlBoxScoreTeam struct {
+	Team struct {
+		ID           int    `json:"id"`
+		Name         string `json:"name"`
+		Abbreviation string `json:"abbreviation"`
+	} `json:"team"`
+	TeamStats struct {
+		TeamSkaterStats struct {
+			Goals                  int     `json:"goals"`
+			Pim                    int     `json:"pim"`
+			Shots                  int     `json:"shots"`
+			PowerPlayGoals         float64 `json:"powerPlayGoals"`
+			PowerPlayOpportunities float64 `json:"powerPlayOpportunities"`
+			FaceOffWinPercentage   string  `json:"faceOffWinPercentage"`
+			Blocked                int     `json:"blocked"`
+			Takeaways              int     `json:"takeaways"`
+			Giveaways              int     `json:"giveaways"`
+			Hits                   int     `json:"hits"`
+		} `json:"teamSkaterStats"`
+	} `json:"teamStats"`
+	Players map[string]nhlBoxScorePlayer `json:"players"` // Key is ID + player id
+}
+
+type nhlBoxScorePlayer struct {
+	Person struct {
+		ID       int    `json:"id"`
+		FullName string `json:"fullName"`
+	} `json:"person"`
+	JerseyNumber string `json:"jerseyNumber"`
+	Position     struct {
+		Abbreviation string `json:"abbreviation"`
+	} `json:"position"`
+	Stats struct {
+		SkaterStats *struct {
+			TimeOnIce      string `json:"timeOnIce"`
+			Goals          int    `json:"goals"`
+			Assists        int    `json:"assists"`
+			Shots          int    `json:"shots"`
+			Hits           int    `json:"hits"`
+			PowerPlayGoals int    `json:"powerPlayGoals"`
+			PenaltyMinutes int    `json:"penaltyMinutes"`
+			FaceOffWins    int    `json:"faceOffWins"`
+			FaceoffTaken   int    `json:"faceoffTaken"`
+			Takeaways      int    `json:"takeaways"`
+			Giveaways      int    `json:"giveaways"`
+			Blocked        int    `json:"blocked"`
+			PlusMinus      int    `json:"plusMinus"`
+		} `json:"skaterStats"`
+		GoalieStats *struct {
+			TimeOnIce      string  `json:"timeOnIce"`
+			Goals          int     `json:"goals"`
+			Assists        int     `json:"assists"`
+			Shots          int     `json:"shots"`
+			Saves          int     `json:"saves"`
+			SavePercentage float64 `json:"savePercentage"`
+			PenaltyMinutes int     `json:"pim"`
+		} `json:"goalieStats"`
+	} `json:"stats"`
+}
+
+// BoxScore returns the box score in the live feed of a game, see liveBoxScore
+func (n *nhl) BoxScore(params url.Values) map[string]interface{} {
+	id, _ := strconv.Atoi(params.Get("gameId"))
+	boxScore, state, status := n.liveBoxScore(id)
+	result := make(map[string]interface{})
+	result["home"] = buildTeamFromNHLBoxScore(&boxScore.Teams.Home)
+	result["away"] = buildTeamFromNHLBoxScore(&boxScore.Teams.Away)
+	result["metadata"] = map[string]interface{}{"state": state}
+	return setUpstreamStatus(result, status)
+}
+
+func buildTeamFromNHLBoxScore(team *nhlBoxScoreTeam) map[string]interface{} {
+	teamStats := team.TeamStats.TeamSkaterStats
+	result := make(map[string]interface{})
+	result["teamId"] = team.Team.ID
+	result["name"] = team.Team.Name
+	result["abbr"] = team.Team.Abbreviation
+	result["stats"] = map[string]interface{}{
+		"goals":                  teamStats.Goals,
+		"shots":                  teamStats.Shots,
+		"hits":                   teamStats.Hits,
+		"penaltyMinutes":         teamStats.Pim,
+		"powerPlayGoals":         int(teamStats.PowerPlayGoals),
+		"powerPlayOpportunities": int(teamStats.PowerPlayOpportunities),
+		"faceOffWinPercentage":   teamStats.FaceOffWinPercentage,
+		"blocked":                teamStats.Blocked,
+		"takeaways":              teamStats.Takeaways,
+		"giveaways":              teamStats.Giveaways,
+	}
+	players := make([]map[string]interface{}, 0, len(team.Players))
+	for _, player := range team.Players {
+		stats := buildStatsFromNHLBoxScorePlayer(&player)
+		if stats == nil { // Scratched
+			continue
+		}
+		players = append(players, map[string]interface{}{
+			"id":       player.Person.ID,
+			"name":     player.Person.FullName,
+			"number":   player.JerseyNumber,
+			"position": player.Position.Abbreviation,
+			"stats":    stats,
+		})
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return players[i]["id"].(int) < players[j]["id"].(int)
+	})
+	result["players"] = players
+	return result
+}
+
+func buildStatsFromNHLBoxScorePlayer(player *nhlBoxScorePlayer) map[string]interface{} {
+	if skater := player.Stats.SkaterStats; skater != nil {
+		return map[string]interface{}{
+			"timeOnIce":      skater.TimeOnIce,
+			"goals":          skater.Goals,
+			"assists":        skater.Assists,
+			"points":         skater.Goals + skater.Assists,
+			"shots":          skater.Shots,
+			"hits":           skater.Hits,
+			"plusMinus":      skater.PlusMinus,
+			"penaltyMinutes": skater.PenaltyMinutes,
+			"powerPlayGoals": skater.PowerPlayGoals,
+			"faceOffWins":    skater.FaceOffWins,
+			"faceOffsTaken":  skater.FaceoffTaken,
+			"takeaways":      skater.Takeaways,
+			"giveaways":      skater.Giveaways,
+			"blocked":        skater.Blocked,
+		}
+	}
+	if goalie := player.Stats.GoalieStats; goalie != nil {
+		return map[string]interface{}{
+			"timeOnIce":      goalie.TimeOnIce,
+			"goals":          goalie.Goals,
+			"assists":        goalie.Assists,
+			"shotsAgainst":   goalie.Shots,
+			"saves":          goalie.Saves,
+			"savePercentage": goalie.SavePercentage,
+			"penaltyMinutes": goalie.PenaltyMinutes,
+		}
+	}
+	return nil
+}
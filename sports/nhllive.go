@@ -0,0 +1,183 @@
+package sports
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/evanphx/json-patch"
+	"github.com/henrymxu/gonhl"
+	"github.com/ngaut/log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const nhlBaseUrl = "https://statsapi.web.nhl.com/api/v1/"
+const nhlLiveEndpoint = "game/%d/feed/live"
+const nhlDiffPatchEndpoint = "game/%d/feed/live/diffPatch?startTimecode=%s"
+
+const nhlLiveGameIdleDuration = 10 * time.Minute // Copies of live feeds that were not requested for this long are dropped
+
+// nhlLiveGame is an in memory copy of a game's live feed, kept up to date with the diffPatch feed
+type nhlLiveGame struct {
+	document []byte    // The live feed JSON
+	timecode string    // metaData.timeStamp of document, yyyymmdd_hhmmss
+	lastUsed time.Time // Guarded by nhl.liveGamesMutex
+	mutex    sync.Mutex
+}
+
+type nhlFeedMetaData struct {
+	MetaData struct {
+		TimeStamp string `json:"timeStamp"`
+	} `json:"metaData"`
+}
+
+type nhlLiveFeed struct {
+	nhlFeedMetaData
+	LiveData gonhl.LiveData `json:"liveData"`
+}
+
+// nhlLiveBoxScore is the box score in the live feed, it has the same structure as the game/{id}/boxscore endpoint
+type nhlLiveBoxScore struct {
+	GameData struct {
+		Status struct {
+			StatusCode string `json:"statusCode"`
+		} `json:"status"`
+	} `json:"gameData"`
+	LiveData struct {
+		Boxscore nhlBoxScore `json:"boxscore"`
+	} `json:"liveData"`
+}
+
+type nhlDiffPatch struct {
+	Diff json.RawMessage `json:"diff"`
+}
+
+// liveGame returns the in memory copy of a game's live feed, or nil if the game is not followed
+func (n *nhl) liveGame(id int) *nhlLiveGame {
+	n.liveGamesMutex.Lock()
+	defer n.liveGamesMutex.Unlock()
+	game, ok := n.liveGames[id]
+	if !ok {
+		return nil
+	}
+	game.lastUsed = time.Now()
+	return game
+}
+
+// addLiveGame follows a game whose full feed was fetched, dropping the copies that have been idle for too long.
+// Games are only followed once their feed is fetched, so ids that do not exist are never kept.
+func (n *nhl) addLiveGame(id int, game *nhlLiveGame) {
+	n.liveGamesMutex.Lock()
+	defer n.liveGamesMutex.Unlock()
+	for liveId, liveGame := range n.liveGames {
+		if time.Since(liveGame.lastUsed) > nhlLiveGameIdleDuration {
+			delete(n.liveGames, liveId)
+		}
+	}
+	if _, ok := n.liveGames[id]; !ok { // Another request could have followed the game meanwhile
+		game.lastUsed = time.Now()
+		n.liveGames[id] = game
+	}
+}
+
+// removeLiveGame drops the in memory copy of a game's live feed, once the game is complete
+func (n *nhl) removeLiveGame(id int) {
+	n.liveGamesMutex.Lock()
+	defer n.liveGamesMutex.Unlock()
+	delete(n.liveGames, id)
+}
+
+// getGameLiveData returns the live data of a game, patching the previous copy of the feed with the diffPatch feed
+// and falling back to the full feed if there is no previous copy or the patches cannot be applied.
+func (n *nhl) getGameLiveData(id int) (gonhl.LiveData, int) {
+	game := n.liveGame(id)
+	followed := game != nil
+	if !followed {
+		game = &nhlLiveGame{}
+	}
+	game.mutex.Lock()
+	defer game.mutex.Unlock()
+	status := http.StatusOK
+	if game.document == nil || !game.patch(id) {
+		status = game.fetch(id)
+	}
+	if !followed && game.document != nil {
+		n.addLiveGame(id, game)
+	}
+	feed := nhlLiveFeed{}
+	if game.document != nil {
+		if err := json.Unmarshal(game.document, &feed); err != nil {
+			log.Errorf("Error decoding NHL live feed for %d: %s", id, err)
+		}
+	}
+	return feed.LiveData, status
+}
+
+// liveBoxScore returns the box score in the live feed of a game, and the game's state.
+// The copy of the feed kept up to date by PlayByPlay is used while the game is being followed, so the watcher does not
+// request the box score separately, otherwise the full feed is requested.
+func (n *nhl) liveBoxScore(id int) (nhlBoxScore, ScheduleState, int) {
+	game := n.liveGame(id)
+	var document []byte
+	if game != nil {
+		game.mutex.Lock()
+		document = game.document
+		game.mutex.Unlock()
+	}
+	status := http.StatusOK
+	if document == nil {
+		fetched := json.RawMessage{}
+		status = fetchJSON(nhlBaseUrl+fmt.Sprintf(nhlLiveEndpoint, id), nil, &fetched)
+		document = fetched
+	}
+	feed := nhlLiveBoxScore{}
+	if status == http.StatusOK {
+		if err := json.Unmarshal(document, &feed); err != nil {
+			log.Errorf("Error decoding NHL live feed box score for %d: %s", id, err)
+		}
+	}
+	statusCode, _ := strconv.Atoi(feed.GameData.Status.StatusCode)
+	return feed.LiveData.Boxscore, n.ParseScheduleState(statusCode), status
+}
+
+// fetch replaces the copy of the feed with the full feed
+func (g *nhlLiveGame) fetch(id int) int {
+	document := json.RawMessage{}
+	status := fetchJSON(nhlBaseUrl+fmt.Sprintf(nhlLiveEndpoint, id), nil, &document)
+	if status != http.StatusOK {
+		return status
+	}
+	g.document = document
+	g.updateTimecode()
+	return status
+}
+
+// patch applies the diffPatch feed since the copy's timecode, returning false if the copy could not be updated
+func (g *nhlLiveGame) patch(id int) bool {
+	var diffPatches []nhlDiffPatch
+	status := fetchJSON(nhlBaseUrl+fmt.Sprintf(nhlDiffPatchEndpoint, id, g.timecode), nil, &diffPatches)
+	if status != http.StatusOK {
+		return false
+	}
+	document := g.document
+	for _, diffPatch := range diffPatches {
+		patch, err := jsonpatch.DecodePatch(diffPatch.Diff)
+		if err == nil {
+			document, err = patch.Apply(document)
+		}
+		if err != nil {
+			log.Errorf("Error applying NHL diffPatch for %d: %s", id, err)
+			return false
+		}
+	}
+	g.document = document
+	g.updateTimecode()
+	return true
+}
+
+func (g *nhlLiveGame) updateTimecode() {
+	feed := nhlFeedMetaData{}
+	_ = json.Unmarshal(g.document, &feed)
+	g.timecode = feed.MetaData.TimeStamp
+}
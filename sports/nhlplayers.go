@@ -0,0 +1,116 @@
+package sports
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const nhlPlayerEndpoint = "people/%s"
+const nhlPlayerStatsEndpoint = "people/%s/stats?stats=yearByYear,careerRegularSeason"
+const nhlHeadshotUrl = "https://cms.nhl.bamgrid.com/images/headshots/current/168x168/%d.jpg"
+
+type nhlPeople struct {
+	People []struct {
+		ID            int    `json:"id"`
+		FullName      string `json:"fullName"`
+		FirstName     string `json:"firstName"`
+		LastName      string `json:"lastName"`
+		PrimaryNumber string `json:"primaryNumber"`
+		BirthDate     string `json:"birthDate"`
+		BirthCity     string `json:"birthCity"`
+		BirthCountry  string `json:"birthCountry"`
+		Nationality   string `json:"nationality"`
+		Height        string `json:"height"`
+		Weight        int    `json:"weight"`
+		Active        bool   `json:"active"`
+		ShootsCatches string `json:"shootsCatches"`
+		CurrentTeam   struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		} `json:"currentTeam"`
+		PrimaryPosition struct {
+			Abbreviation string `json:"abbreviation"`
+			Name         string `json:"name"`
+		} `json:"primaryPosition"`
+	} `json:"people"`
+}
+
+type nhlPlayerStats struct {
+	Stats []struct {
+		Type struct {
+			DisplayName string `json:"displayName"`
+		} `json:"type"`
+		Splits []struct {
+			Season string `json:"season"`
+			Team   struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"team"`
+			League struct {
+				Name string `json:"name"`
+			} `json:"league"`
+			Stat map[string]interface{} `json:"stat"`
+		} `json:"splits"`
+	} `json:"stats"`
+}
+
+func (n *nhl) Player(params url.Values) map[string]interface{} {
+	people := nhlPeople{}
+	status := fetchJSON(nhlBaseUrl+fmt.Sprintf(nhlPlayerEndpoint, url.PathEscape(params.Get("playerId"))), nil, &people)
+	if status == http.StatusOK && len(people.People) == 0 {
+		status = http.StatusNotFound
+	}
+	result := make(map[string]interface{})
+	for _, person := range people.People {
+		player := map[string]interface{}{
+			"playerId":      person.ID,
+			"name":          person.FullName,
+			"firstName":     person.FirstName,
+			"lastName":      person.LastName,
+			"number":        person.PrimaryNumber,
+			"position":      person.PrimaryPosition.Abbreviation,
+			"birthDate":     person.BirthDate,
+			"birthPlace":    fmt.Sprintf("%s, %s", person.BirthCity, person.BirthCountry),
+			"nationality":   person.Nationality,
+			"height":        person.Height,
+			"weight":        person.Weight,
+			"active":        person.Active,
+			"shootsCatches": person.ShootsCatches,
+			"headshot":      fmt.Sprintf(nhlHeadshotUrl, person.ID),
+		}
+		if person.CurrentTeam.ID != 0 {
+			player["team"] = TeamId(n.Name(), person.CurrentTeam.ID)
+			player["teamName"] = person.CurrentTeam.Name
+		}
+		result["content"] = player
+	}
+	return setUpstreamStatus(result, status)
+}
+
+func (n *nhl) PlayerStats(params url.Values) map[string]interface{} {
+	stats := nhlPlayerStats{}
+	status := fetchJSON(nhlBaseUrl+fmt.Sprintf(nhlPlayerStatsEndpoint, url.PathEscape(params.Get("playerId"))), nil, &stats)
+	seasons := make([]map[string]interface{}, 0)
+	career := make(map[string]interface{})
+	for _, statType := range stats.Stats {
+		for _, split := range statType.Splits {
+			switch statType.Type.DisplayName {
+			case "yearByYear":
+				season := map[string]interface{}{
+					"season":   split.Season,
+					"league":   split.League.Name,
+					"teamName": split.Team.Name,
+					"stats":    split.Stat,
+				}
+				if split.League.Name == "National Hockey League" {
+					season["team"] = TeamId(n.Name(), split.Team.ID)
+				}
+				seasons = append(seasons, season)
+			case "careerRegularSeason":
+				career = split.Stat
+			}
+		}
+	}
+	return setUpstreamStatus(map[string]interface{}{"content": map[string]interface{}{"seasons": seasons, "career": career}}, status)
+}
@@ -0,0 +1,72 @@
+package sports
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const nhlStandingsEndpoint = "standings?expand=standings.record,standings.team"
+
+type nhlStandings struct {
+	Records []struct {
+		Division struct {
+			Name string `json:"name"`
+		} `json:"division"`
+		Conference struct {
+			Name string `json:"name"`
+		} `json:"conference"`
+		TeamRecords []struct {
+			Team struct {
+				ID           int    `json:"id"`
+				Name         string `json:"name"`
+				Abbreviation string `json:"abbreviation"` // Only with the standings.team expand
+			} `json:"team"`
+			LeagueRecord nhlStandingsRecord `json:"leagueRecord"`
+			Points       int                `json:"points"`
+			GamesPlayed  int                `json:"gamesPlayed"`
+			Streak       struct {
+				StreakCode string `json:"streakCode"`
+			} `json:"streak"`
+			Records struct {
+				OverallRecords []nhlStandingsRecord `json:"overallRecords"`
+			} `json:"records"`
+		} `json:"teamRecords"`
+	} `json:"records"`
+}
+
+type nhlStandingsRecord struct {
+	Wins   int    `json:"wins"`
+	Losses int    `json:"losses"`
+	Ot     int    `json:"ot"`
+	Type   string `json:"type"`
+}
+
+func (n *nhl) Standings(params url.Values) map[string]interface{} {
+	standings := nhlStandings{}
+	status := fetchJSON(nhlBaseUrl+nhlStandingsEndpoint, nil, &standings)
+	var teams []standingsTeam
+	for _, record := range standings.Records {
+		for _, teamRecord := range record.TeamRecords {
+			team := standingsTeam{
+				teamId:      teamRecord.Team.ID,
+				name:        teamRecord.Team.Name,
+				abbr:        teamRecord.Team.Abbreviation,
+				conference:  record.Conference.Name,
+				division:    record.Division.Name,
+				wins:        teamRecord.LeagueRecord.Wins,
+				losses:      teamRecord.LeagueRecord.Losses,
+				ot:          teamRecord.LeagueRecord.Ot,
+				points:      teamRecord.Points,
+				gamesPlayed: teamRecord.GamesPlayed,
+				streak:      teamRecord.Streak.StreakCode,
+			}
+			for _, overallRecord := range teamRecord.Records.OverallRecords {
+				if overallRecord.Type == "lastTen" {
+					team.lastTen = fmt.Sprintf("%d-%d-%d", overallRecord.Wins, overallRecord.Losses, overallRecord.Ot)
+				}
+			}
+			teams = append(teams, team)
+		}
+	}
+	return setUpstreamStatus(buildResultFromStandings(teams, true), status)
+}
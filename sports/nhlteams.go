@@ -0,0 +1,76 @@
+package sports
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const nhlTeamsEndpoint = "teams"
+const nhlRosterEndpoint = "teams/%s/roster"
+
+type nhlTeams struct {
+	Teams []struct {
+		ID           int    `json:"id"`
+		Name         string `json:"name"`
+		Abbreviation string `json:"abbreviation"`
+		LocationName string `json:"locationName"`
+		TeamName     string `json:"teamName"`
+		Venue        struct {
+			Name string `json:"name"`
+		} `json:"venue"`
+		Division struct {
+			Name string `json:"name"`
+		} `json:"division"`
+		Conference struct {
+			Name string `json:"name"`
+		} `json:"conference"`
+	} `json:"teams"`
+}
+
+type nhlRoster struct {
+	Roster []struct {
+		Person struct {
+			ID       int    `json:"id"`
+			FullName string `json:"fullName"`
+		} `json:"person"`
+		JerseyNumber string `json:"jerseyNumber"`
+		Position     struct {
+			Abbreviation string `json:"abbreviation"`
+		} `json:"position"`
+	} `json:"roster"`
+}
+
+func (n *nhl) Teams(params url.Values) map[string]interface{} {
+	teams := nhlTeams{}
+	status := fetchJSON(nhlBaseUrl+nhlTeamsEndpoint, nil, &teams)
+	content := make([]map[string]interface{}, len(teams.Teams))
+	for i, team := range teams.Teams {
+		content[i] = map[string]interface{}{
+			"id":         TeamId(n.Name(), team.ID),
+			"teamId":     team.ID,
+			"name":       team.Name,
+			"abbr":       team.Abbreviation,
+			"location":   team.LocationName,
+			"nickname":   team.TeamName,
+			"conference": team.Conference.Name,
+			"division":   team.Division.Name,
+			"venue":      team.Venue.Name,
+		}
+	}
+	return setUpstreamStatus(map[string]interface{}{"content": content}, status)
+}
+
+func (n *nhl) Roster(params url.Values) map[string]interface{} {
+	roster := nhlRoster{}
+	status := fetchJSON(nhlBaseUrl+fmt.Sprintf(nhlRosterEndpoint, url.PathEscape(params.Get("teamId"))), nil, &roster)
+	content := make([]map[string]interface{}, len(roster.Roster))
+	for i, player := range roster.Roster {
+		content[i] = map[string]interface{}{
+			"playerId": player.Person.ID,
+			"name":     player.Person.FullName,
+			"number":   player.JerseyNumber,
+			"position": player.Position.Abbreviation,
+		}
+	}
+	return setUpstreamStatus(map[string]interface{}{"content": content}, status)
+}
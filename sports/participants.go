@@ -0,0 +1,37 @@
+package sports
+
+// ParticipantRole is what a player did in a play
+type ParticipantRole string
+
+const (
+	Scorer         ParticipantRole = "scorer"
+	Assister       ParticipantRole = "assister"
+	Goalie         ParticipantRole = "goalie"
+	Shooter        ParticipantRole = "shooter"
+	Blocker        ParticipantRole = "blocker"
+	Hitter         ParticipantRole = "hitter"
+	Hittee         ParticipantRole = "hittee"
+	Winner         ParticipantRole = "winner" // Faceoffs and jump balls
+	Loser          ParticipantRole = "loser"
+	Penalized      ParticipantRole = "penalized" // Penalties and fouls
+	DrewPenalty    ParticipantRole = "drewPenalty"
+	ServedBy       ParticipantRole = "servedBy"
+	Rebounder      ParticipantRole = "rebounder"
+	TurnedOver     ParticipantRole = "turnedOver"
+	Stealer        ParticipantRole = "stealer"
+	SubstitutedIn  ParticipantRole = "substitutedIn"
+	SubstitutedOut ParticipantRole = "substitutedOut"
+	Player         ParticipantRole = "player" // Any other participation
+)
+
+// buildParticipant creates a participant of a play, team is the abbreviation of the player's team
+func buildParticipant(role ParticipantRole, playerId interface{}, name string, number string, team string, teamId interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"role":     role,
+		"playerId": playerId,
+		"name":     name,
+		"number":   number,
+		"team":     team,
+		"teamId":   teamId,
+	}
+}
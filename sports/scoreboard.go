@@ -0,0 +1,97 @@
+package sports
+
+import (
+	"sort"
+)
+
+// Games that are being played are listed before games that have not started, then finished and postponed games
+var scoreboardStateOrder = map[ScheduleState]int{
+	Live:         0,
+	Intermission: 1,
+	Preview:      2,
+	Complete:     3,
+	Postponed:    4,
+}
+
+// BuildScoreboardGame converts a scheduled game of sport into the sport independent representation of the scoreboard
+func BuildScoreboardGame(sport Sport, game ScheduledGame) map[string]interface{} {
+	scoreboardGame := map[string]interface{}{
+		"sport":     sport.Name(),
+		"gameId":    game.Id,
+		"startTime": CreateDetailedStringFromDate(game.StartTime),
+		"state":     game.ScheduleState,
+		"status":    game.Details["status"],
+		"period":    game.Details["period"],
+		"clock":     game.Details["time"],
+		"venue":     game.Details["venue"],
+	}
+	for _, side := range []string{"home", "away"} {
+		team, _ := game.Details[side].(map[string]interface{})
+		scoreboardGame[side] = map[string]interface{}{
+			"id":     team["id"],
+			"teamId": team["teamId"],
+			"name":   team["name"],
+			"abbr":   team["abbr"],
+			"score":  team["score"],
+		}
+	}
+	return scoreboardGame
+}
+
+// BuildScoreboardUpdate extracts the score, clock and state of a game of sport from its playbyplay
+func BuildScoreboardUpdate(sport Sport, gameId string, playbyplay map[string]interface{}) map[string]interface{} {
+	update := map[string]interface{}{
+		"sport":  sport.Name(),
+		"gameId": gameId,
+	}
+	if metadata, ok := playbyplay["metadata"].(map[string]interface{}); ok {
+		update["state"] = metadata["state"]
+	}
+	game, _ := playbyplay["game"].(map[string]interface{})
+	if status, ok := game["status"].(map[string]interface{}); ok {
+		update["period"] = status["period"]
+		update["clock"] = status["periodTimeRemaining"]
+	}
+	for _, side := range []string{"home", "away"} {
+		if team, ok := game[side].(map[string]interface{}); ok {
+			update[side] = map[string]interface{}{"score": team["score"]}
+		}
+	}
+	return update
+}
+
+// ApplyScoreboardUpdate replaces the score, clock and state of a scoreboard game with those of update
+func ApplyScoreboardUpdate(scoreboardGame map[string]interface{}, update map[string]interface{}) {
+	for _, key := range []string{"state", "period", "clock"} {
+		if value, ok := update[key]; ok {
+			scoreboardGame[key] = value
+		}
+	}
+	for _, side := range []string{"home", "away"} {
+		team, ok := scoreboardGame[side].(map[string]interface{})
+		updateTeam, updateOk := update[side].(map[string]interface{})
+		if ok && updateOk {
+			team["score"] = updateTeam["score"]
+		}
+	}
+}
+
+// SortScoreboard orders scoreboard games by start time, then by state, sport and game id
+func SortScoreboard(games []map[string]interface{}) {
+	sort.SliceStable(games, func(i, j int) bool {
+		startI, _ := CreateDateFromDetailedString(games[i]["startTime"].(string))
+		startJ, _ := CreateDateFromDetailedString(games[j]["startTime"].(string))
+		if !startI.Equal(startJ) {
+			return startI.Before(startJ)
+		}
+		stateI, _ := games[i]["state"].(ScheduleState)
+		stateJ, _ := games[j]["state"].(ScheduleState)
+		if scoreboardStateOrder[stateI] != scoreboardStateOrder[stateJ] {
+			return scoreboardStateOrder[stateI] < scoreboardStateOrder[stateJ]
+		}
+		if games[i]["sport"] != games[j]["sport"] {
+			return games[i]["sport"].(string) < games[j]["sport"].(string)
+		}
+		return games[i]["gameId"].(string) < games[j]["gameId"].(string)
+	})
+}
@@ -3,7 +3,6 @@ package sports
 import (
 	"net/url"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -19,6 +18,7 @@ const (
 	Live         ScheduleState = 1
 	Intermission ScheduleState = 2
 	Complete     ScheduleState = 3
+	Postponed    ScheduleState = 4
 )
 
 type ScheduledGame struct {
@@ -29,6 +29,7 @@ type ScheduledGame struct {
 }
 
 // params[] date
+// Schedule and PlayByPlay return nil if the league's api is unavailable
 type Sport interface {
 	Name() string
 	Schedule(params url.Values) map[string]interface{}
@@ -37,8 +38,30 @@ type Sport interface {
 	DefaultTimeString() string
 }
 
-func InitializeSports() *Sports {
-	sports := Sports{InitNHL(), InitNBA()}
+// SportNames are the names of the sports that can be enabled, in their default order
+var SportNames = []string{"nhl", "nba"}
+
+// sportProviders create each sport from its providers (in order of preference)
+var sportProviders = map[string]func() Sport{
+	"nhl": func() Sport { return composeSport(InitNHL()) },
+	"nba": func() Sport { return composeSport(InitNBA(), InitNBAStats()) },
+}
+
+// InitializeSports creates the enabled sports (by name, in order) from their providers,
+// caching their results and limiting their upstream requests with limits (by sport name)
+func InitializeSports(enabled []string, limits map[string]UpstreamLimit) *Sports {
+	sports := make(Sports, 0, len(enabled))
+	for _, name := range enabled {
+		provider, ok := sportProviders[name]
+		if !ok {
+			continue
+		}
+		limit, ok := limits[name]
+		if !ok {
+			limit = DefaultUpstreamLimit
+		}
+		sports = append(sports, cacheSport(limitSport(provider(), limit)))
+	}
 	return &sports
 }
 
@@ -46,17 +69,12 @@ func (s *Sports) ParseSportId(sportId int) Sport {
 	return (*s)[sportId]
 }
 
-func ParseSportString(sport string) int {
-	strings.ToLower(sport)
-	switch sport {
-	case "nhl":
-		return 0
-	case "nba":
-		return 1
-	case "nfl":
-		return 2
-	case "mlb":
-		return 3
+// ParseSportString returns the id of an enabled sport by name, or -1 if it is not enabled
+func (s *Sports) ParseSportString(sport string) int {
+	for i, enabled := range *s {
+		if enabled.Name() == sport {
+			return i
+		}
 	}
 	return -1
 }
@@ -89,6 +107,76 @@ func CheckActiveGames(sport Sport, schedule map[string]interface{}) []ScheduledG
 	return games
 }
 
+// StatusUnreachable is the upstream status of results whose upstream request could not be made or decoded
+const StatusUnreachable = -1
+
+// UpstreamStatus returns the status code of the upstream request that produced result, StatusUnreachable if the request
+// failed, or 0 if it is unknown
+func UpstreamStatus(result map[string]interface{}) int {
+	if metadata, ok := result["metadata"].(map[string]interface{}); ok {
+		if status, ok := metadata["status"].(int); ok {
+			return status
+		}
+	}
+	return 0
+}
+
+// setUpstreamStatus records the status code of the upstream request that produced result in its metadata
+func setUpstreamStatus(result map[string]interface{}, status int) map[string]interface{} {
+	metadata, ok := result["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = make(map[string]interface{})
+		result["metadata"] = metadata
+	}
+	if status == 0 { // The clients and fetchJSON return 0 when the request fails
+		status = StatusUnreachable
+	}
+	metadata["status"] = status
+	return result
+}
+
+// copyResult returns a copy of result whose maps and lists of maps are not shared with result,
+// so that callers sharing a result can each modify their copy
+func copyResult(result map[string]interface{}) map[string]interface{} {
+	if result == nil {
+		return nil
+	}
+	return copyResultValue(result).(map[string]interface{})
+}
+
+func copyResultValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if value == nil {
+			return value
+		}
+		copied := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			copied[key] = copyResultValue(item)
+		}
+		return copied
+	case []map[string]interface{}:
+		if value == nil {
+			return value
+		}
+		copied := make([]map[string]interface{}, len(value))
+		for i, item := range value {
+			copied[i] = copyResultValue(item).(map[string]interface{})
+		}
+		return copied
+	case []interface{}:
+		if value == nil {
+			return value
+		}
+		copied := make([]interface{}, len(value))
+		for i, item := range value {
+			copied[i] = copyResultValue(item)
+		}
+		return copied
+	}
+	return value
+}
+
 // CreateDetailedStringFromDate converts a time.Time object to a string representing a date with format `yyyy-mm-dd`.
 func CreateDetailedStringFromDate(date time.Time) string {
 	return date.Format(DetailedDateLayout)
@@ -0,0 +1,106 @@
+package sports
+
+import (
+	"sort"
+)
+
+// standingsTeam is a team's record, shared by the providers of every sport
+type standingsTeam struct {
+	teamId      interface{}
+	name        string
+	abbr        string
+	conference  string
+	division    string
+	wins        int
+	losses      int
+	ot          int // Overtime losses, points based sports only
+	points      int // Points based sports only
+	gamesPlayed int
+	streak      string // W3, L1 or OT2
+	lastTen     string // Record of the last 10 games
+}
+
+// buildResultFromStandings groups teams by league, conference and division, each ordered by points (if pointsBased)
+// or by win percentage, with points or games back from the group's leader
+func buildResultFromStandings(teams []standingsTeam, pointsBased bool) map[string]interface{} {
+	sort.Slice(teams, func(i, j int) bool {
+		if pointsBased && teams[i].points != teams[j].points {
+			return teams[i].points > teams[j].points
+		} else if pointsBased && teams[i].gamesPlayed != teams[j].gamesPlayed {
+			return teams[i].gamesPlayed < teams[j].gamesPlayed
+		} else if !pointsBased && teams[i].winPercentage() != teams[j].winPercentage() {
+			return teams[i].winPercentage() > teams[j].winPercentage()
+		}
+		return teams[i].name < teams[j].name
+	})
+	var conferences, divisions []string
+	conferenceTeams := make(map[string][]standingsTeam)
+	divisionTeams := make(map[string][]standingsTeam)
+	divisionConferences := make(map[string]string)
+	for _, team := range teams {
+		if _, ok := conferenceTeams[team.conference]; !ok {
+			conferences = append(conferences, team.conference)
+		}
+		conferenceTeams[team.conference] = append(conferenceTeams[team.conference], team)
+		if _, ok := divisionTeams[team.division]; !ok {
+			divisions = append(divisions, team.division)
+		}
+		divisionTeams[team.division] = append(divisionTeams[team.division], team)
+		divisionConferences[team.division] = team.conference
+	}
+	sort.Strings(conferences)
+	sort.Strings(divisions)
+	result := make(map[string]interface{})
+	result["league"] = buildStandingsGroup(teams, pointsBased)
+	conferenceResults := make([]map[string]interface{}, len(conferences))
+	for i, conference := range conferences {
+		conferenceResults[i] = map[string]interface{}{
+			"name":  conference,
+			"teams": buildStandingsGroup(conferenceTeams[conference], pointsBased),
+		}
+	}
+	result["conferences"] = conferenceResults
+	divisionResults := make([]map[string]interface{}, len(divisions))
+	for i, division := range divisions {
+		divisionResults[i] = map[string]interface{}{
+			"name":       division,
+			"conference": divisionConferences[division],
+			"teams":      buildStandingsGroup(divisionTeams[division], pointsBased),
+		}
+	}
+	result["divisions"] = divisionResults
+	return result
+}
+
+func buildStandingsGroup(teams []standingsTeam, pointsBased bool) []map[string]interface{} {
+	group := make([]map[string]interface{}, len(teams))
+	for i, team := range teams {
+		result := make(map[string]interface{})
+		result["rank"] = i + 1
+		result["teamId"] = team.teamId
+		result["name"] = team.name
+		result["abbr"] = team.abbr
+		result["wins"] = team.wins
+		result["losses"] = team.losses
+		result["gamesPlayed"] = team.gamesPlayed
+		result["streak"] = team.streak
+		result["lastTen"] = team.lastTen
+		if pointsBased {
+			result["ot"] = team.ot
+			result["points"] = team.points
+			result["pointsBack"] = teams[0].points - team.points
+		} else {
+			result["winPercentage"] = team.winPercentage()
+			result["gamesBack"] = float64((teams[0].wins-team.wins)+(team.losses-teams[0].losses)) / 2
+		}
+		group[i] = result
+	}
+	return group
+}
+
+func (t *standingsTeam) winPercentage() float64 {
+	if t.wins+t.losses == 0 {
+		return 0
+	}
+	return float64(t.wins) / float64(t.wins+t.losses)
+}
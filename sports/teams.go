@@ -0,0 +1,16 @@
+package sports
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TeamId returns the stable identifier of a team across sports (nhl-10), from the league's id of the team
+func TeamId(sport string, leagueTeamId interface{}) string {
+	return fmt.Sprintf("%s-%v", sport, leagueTeamId)
+}
+
+// ParseTeamId returns the league's id of a team of sport from its stable identifier (nhl-10) or from the league's id (10)
+func ParseTeamId(sport Sport, teamId string) string {
+	return strings.TrimPrefix(teamId, sport.Name()+"-")
+}
@@ -0,0 +1,164 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/henrymxu/gosports/sports"
+	"github.com/ngaut/log"
+	"sort"
+	"time"
+)
+
+const CompletedGamesCollectionName = "completedGames"
+
+const maxCompletedGames = 50 // Completed games retained per sport
+
+// CompletedGame is the final snapshot of a watched game
+type CompletedGame struct {
+	Id         string
+	StartTime  time.Time
+	Completed  time.Time              // When the watcher saw the game go final
+	Details    map[string]interface{} // The game in the sport's schedule
+	PlayByPlay map[string]interface{} // The latest playbyplay of the game with every play, see GetLatestPlayByPlay
+}
+
+// completedDocument is a CompletedGame stored in the sport's database, so the completed games outlive the server
+type completedDocument struct {
+	Id         string    `bson:"_id"`
+	StartTime  time.Time `bson:"startTime"`
+	Completed  time.Time `bson:"completed"`
+	Details    string    `bson:"details"`    // JSON
+	PlayByPlay string    `bson:"playByPlay"` // JSON
+}
+
+// GetCompletedGames returns the most recently completed games of sport that were watched, most recent first.
+// The games must not be modified.
+func (s *Server) GetCompletedGames(sport sports.Sport) []CompletedGame {
+	s.completedMutex.RLock()
+	defer s.completedMutex.RUnlock()
+	games := make([]CompletedGame, len(s.completed[sport.Name()]))
+	copy(games, s.completed[sport.Name()])
+	return games
+}
+
+// loadCompletedGames reads the completed games of every sport stored by recordCompletedGame
+func (s *Server) loadCompletedGames() {
+	for _, sport := range *s.sports {
+		var stored []completedDocument
+		collection := s.databaseServer.GetDatabase(sport.Name()).Collection(CompletedGamesCollectionName)
+		if err := collection.FindDocuments(context.Background(), &stored); err != nil {
+			log.Errorf("Error loading %s completed games: %s", sport.Name(), err)
+			continue
+		}
+		sort.Slice(stored, func(i, j int) bool {
+			return stored[i].Completed.After(stored[j].Completed)
+		})
+		if len(stored) > maxCompletedGames {
+			stored = stored[:maxCompletedGames]
+		}
+		games := make([]CompletedGame, len(stored))
+		for i, document := range stored {
+			games[i] = CompletedGame{
+				Id:         document.Id,
+				StartTime:  document.StartTime,
+				Completed:  document.Completed,
+				Details:    decodeCompletedJSON(document.Details),
+				PlayByPlay: decodeCompletedJSON(document.PlayByPlay),
+			}
+		}
+		s.completed[sport.Name()] = games
+	}
+}
+
+// recordCompletedGame stores the final snapshot of game, dropping the oldest completed game past maxCompletedGames.
+// A game that was already recorded (watched again after a restart) is replaced, keeping when it was completed.
+func (s *Server) recordCompletedGame(sport sports.Sport, game sports.ScheduledGame) {
+	completed := CompletedGame{
+		Id:         game.Id,
+		StartTime:  game.StartTime,
+		Completed:  time.Now(),
+		Details:    game.Details,
+		PlayByPlay: s.GetLatestPlayByPlay(sport, game.Id),
+	}
+	s.completedMutex.Lock()
+	games := s.completed[sport.Name()]
+	replaced := false
+	for i, recorded := range games {
+		if recorded.Id == completed.Id {
+			completed.Completed = recorded.Completed
+			games[i] = completed
+			replaced = true
+			break
+		}
+	}
+	var dropped []CompletedGame
+	if !replaced {
+		games = append([]CompletedGame{completed}, games...)
+		if len(games) > maxCompletedGames {
+			dropped = games[maxCompletedGames:]
+			games = games[:maxCompletedGames]
+		}
+	}
+	s.completed[sport.Name()] = games
+	kept := make(map[string]bool, len(games))
+	for _, game := range games {
+		kept[game.Id] = true
+	}
+	s.completedMutex.Unlock()
+
+	collection := s.databaseServer.GetDatabase(sport.Name()).Collection(CompletedGamesCollectionName)
+	details, _ := json.Marshal(completed.Details)
+	playByPlay, _ := json.Marshal(completed.PlayByPlay)
+	document := completedDocument{
+		Id:         completed.Id,
+		StartTime:  completed.StartTime,
+		Completed:  completed.Completed,
+		Details:    string(details),
+		PlayByPlay: string(playByPlay),
+	}
+	if err := collection.ReplaceDocument(context.Background(), document.Id, &document); err != nil {
+		log.Errorf("Error storing %s completed game %s: %s", sport.Name(), document.Id, err)
+	}
+	for _, game := range dropped {
+		if kept[game.Id] {
+			continue
+		}
+		if err := collection.DeleteDocument(context.Background(), game.Id); err != nil {
+			log.Errorf("Error deleting %s completed game %s: %s", sport.Name(), game.Id, err)
+		}
+	}
+}
+
+// decodeCompletedJSON decodes a stored snapshot, lists of objects are built as []map[string]interface{} like the sports build them
+func decodeCompletedJSON(encoded string) map[string]interface{} {
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(encoded), &decoded); err != nil {
+		log.Errorf("Error decoding stored completed game: %s", err)
+		return nil
+	}
+	return restoreObjectLists(decoded).(map[string]interface{})
+}
+
+func restoreObjectLists(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = restoreObjectLists(item)
+		}
+		return value
+	case []interface{}:
+		objects := make([]map[string]interface{}, 0, len(value))
+		for _, item := range value {
+			object, ok := item.(map[string]interface{})
+			if !ok { // Not a list of objects
+				for i, item := range value {
+					value[i] = restoreObjectLists(item)
+				}
+				return value
+			}
+			objects = append(objects, restoreObjectLists(object).(map[string]interface{}))
+		}
+		return objects
+	}
+	return value
+}
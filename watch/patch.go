@@ -0,0 +1,53 @@
+package watch
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// normalizeSnapshot converts a playbyplay result into its JSON representation (without plays or events),
+// so that snapshots can be compared regardless of the Go types the sport adapters used.
+func normalizeSnapshot(playbyplay map[string]interface{}) map[string]interface{} {
+	snapshot := make(map[string]interface{})
+	for key, value := range playbyplay {
+		if key != "plays" && key != "events" {
+			snapshot[key] = value
+		}
+	}
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return snapshot
+	}
+	normalized := make(map[string]interface{})
+	_ = json.Unmarshal(b, &normalized)
+	return normalized
+}
+
+// createMergePatch creates a JSON merge patch (RFC 7386) that transforms previous into current.
+// Objects are diffed recursively, any other changed value (including arrays) is replaced entirely,
+// and removed keys are set to nil. An empty patch means nothing changed.
+func createMergePatch(previous map[string]interface{}, current map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for key, value := range current {
+		previousValue, ok := previous[key]
+		if !ok {
+			patch[key] = value
+			continue
+		}
+		currentMap, currentIsMap := value.(map[string]interface{})
+		previousMap, previousIsMap := previousValue.(map[string]interface{})
+		if currentIsMap && previousIsMap {
+			if subPatch := createMergePatch(previousMap, currentMap); len(subPatch) > 0 {
+				patch[key] = subPatch
+			}
+		} else if !reflect.DeepEqual(previousValue, value) {
+			patch[key] = value
+		}
+	}
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
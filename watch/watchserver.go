@@ -1,33 +1,81 @@
 package watch
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/henrymxu/gosports/database"
 	"github.com/henrymxu/gosports/sports"
 	"github.com/henrymxu/gosports/websocket"
 	"github.com/ngaut/log"
 	"net/url"
-	"strconv"
+	"sync"
 	"time"
 )
 
 const gameChannelStringFormat = "%s%s"
 
-const scheduleCheckDelay = 1 * time.Hour
-const gameLiveCheckDelay = 20 * time.Second // TODO: change this to a higher value
+// Cadence is how often the watcher polls the leagues' apis
+type Cadence struct {
+	ScheduleCheck    time.Duration   // Delay between checks of the schedules for games to watch
+	GameLiveCheck    time.Duration   // Delay between checks of each watched game
+	StandingsRefresh []time.Duration // Delays after a game goes final to refresh standings
+}
+
+var DefaultCadence = Cadence{
+	ScheduleCheck: 1 * time.Hour,
+	GameLiveCheck: 20 * time.Second, // TODO: change this to a higher value
+	// Leagues take a few minutes to update their standings after a game goes final
+	StandingsRefresh: []time.Duration{0, 2 * time.Minute, 5 * time.Minute},
+}
+
+// Kinds of the messages the watcher sends for a game
+const (
+	PlayByPlayMessage = "plays"    // Sent to the game's channel
+	EventMessage      = "events"   // Sent to the game's channel, the message's Type is the sports.EventType
+	BoxScoreMessage   = "boxscore" // Sent to the game's channel
+	ScoreMessage      = "score"    // Sent to the sport's score channel
+)
+
+// Listener receives every message the watcher sends for a game with its kind, it must not block
+type Listener interface {
+	GameMessage(sport sports.Sport, game sports.ScheduledGame, kind string, message websocket.Message)
+}
 
 type Server struct {
-	clientServer *websocket.Server
+	clientServer   *websocket.Server
 	databaseServer *database.Server
-	gameChannels map[string]*chan websocket.Message
-	sports       *sports.Sports
+	gameChannels   map[string]*chan websocket.Message
+	scoreChannels  map[string]*chan websocket.Message // Score, clock and state updates of every game of a sport, by sport name
+	sports         *sports.Sports
+	latest         map[string]map[string]interface{} // Latest playbyplay of each game, with every play so far
+	latestMutex    sync.RWMutex
+	completed      map[string][]CompletedGame // Most recently completed games of each sport, by sport name
+	completedMutex sync.RWMutex
+	listeners      []Listener
+	listenersMutex sync.RWMutex
+	cadence        Cadence
 }
 
-func CreateWatchServer(clientServer *websocket.Server, sportsInstance *sports.Sports) *Server {
+func CreateWatchServer(clientServer *websocket.Server, databaseServer *database.Server, sportsInstance *sports.Sports,
+	cadence Cadence) *Server {
 	server := &Server{
-		clientServer: clientServer,
-		gameChannels: make(map[string]*chan websocket.Message),
-		sports:       sportsInstance,
+		cadence:        cadence,
+		clientServer:   clientServer,
+		databaseServer: databaseServer,
+		gameChannels:   make(map[string]*chan websocket.Message),
+		scoreChannels:  make(map[string]*chan websocket.Message),
+		sports:         sportsInstance,
+		latest:         make(map[string]map[string]interface{}),
+		completed:      make(map[string][]CompletedGame),
+	}
+	server.loadCompletedGames()
+	for _, sport := range *sportsInstance {
+		scoreChannel := make(chan websocket.Message)
+		server.scoreChannels[sport.Name()] = &scoreChannel
+		clientServer.RegisterChannelChannel <- websocket.RegisterChannel{
+			Channel: &scoreChannel,
+			Action:  true,
+		}
 	}
 	go server.watchScheduleForGamesToWatch()
 	return server
@@ -37,8 +85,57 @@ func (s *Server) GetGameChannel(sport sports.Sport, gameId string) *chan websock
 	return s.gameChannels[fmt.Sprintf(gameChannelStringFormat, sport.Name(), gameId)]
 }
 
+// AddListener registers listener to receive the messages of every game
+func (s *Server) AddListener(listener Listener) {
+	s.listenersMutex.Lock()
+	defer s.listenersMutex.Unlock()
+	s.listeners = append(s.listeners, listener)
+}
+
+// GetScoreChannel returns the channel of the score, clock and state updates of every watched game of sport
+func (s *Server) GetScoreChannel(sport sports.Sport) *chan websocket.Message {
+	return s.scoreChannels[sport.Name()]
+}
+
+// GetLatestPlayByPlay returns the latest playbyplay of a watched game with every play so far,
+// or nil if the game is not being watched. The result must not be modified.
+func (s *Server) GetLatestPlayByPlay(sport sports.Sport, gameId string) map[string]interface{} {
+	s.latestMutex.RLock()
+	defer s.latestMutex.RUnlock()
+	return s.latest[fmt.Sprintf(gameChannelStringFormat, sport.Name(), gameId)]
+}
+
+// updateLatestPlayByPlay stores playbyplay as the latest of a game, appending its plays and events to the previous ones
+func (s *Server) updateLatestPlayByPlay(gameString string, playbyplay map[string]interface{}) {
+	s.latestMutex.Lock()
+	defer s.latestMutex.Unlock()
+	latest := make(map[string]interface{})
+	for key, value := range playbyplay {
+		latest[key] = value
+	}
+	for _, key := range []string{"plays", "events"} {
+		accumulated := make([]map[string]interface{}, 0)
+		if previous, ok := s.latest[gameString][key].([]map[string]interface{}); ok {
+			accumulated = append(accumulated, previous...)
+		}
+		if current, ok := playbyplay[key].([]map[string]interface{}); ok {
+			accumulated = append(accumulated, current...)
+		}
+		latest[key] = accumulated
+	}
+	s.latest[gameString] = latest
+}
+
+// removeLatestPlayByPlay drops the latest playbyplay of a game once it is complete, its final snapshot is kept
+// by recordCompletedGame
+func (s *Server) removeLatestPlayByPlay(sport sports.Sport, gameId string) {
+	s.latestMutex.Lock()
+	defer s.latestMutex.Unlock()
+	delete(s.latest, fmt.Sprintf(gameChannelStringFormat, sport.Name(), gameId))
+}
+
 func (s *Server) watchScheduleForGamesToWatch() {
-	ticker := time.NewTicker(scheduleCheckDelay)
+	ticker := time.NewTicker(s.cadence.ScheduleCheck)
 	for; true; <-ticker.C {
 		s.parseScheduleForGamesToWatch()
 	}
@@ -49,6 +146,9 @@ func (s *Server) parseScheduleForGamesToWatch() {
 		sport := sportType
 		schedule := sport.Schedule(nil)
 		for _, game := range sports.CheckActiveGames(sport, schedule) {
+			if game.ScheduleState == sports.Postponed { // Postponed games are never played on their scheduled date
+				continue
+			}
 			gameId := game.Id
 			gameString := fmt.Sprintf(gameChannelStringFormat, sport.Name(), gameId)
 			if _, ok := s.gameChannels[gameString]; !ok { // ScheduledGame exists in schedule and does not yet have a channel created
@@ -84,37 +184,45 @@ func (s *Server) waitToWatchGame(sport *sports.Sport, game sports.ScheduledGame)
 }
 
 type internalGameStatus struct {
-	state         sports.ScheduleState
-	lastCheck     string
-	currentPeriod int
+	state     sports.ScheduleState
+	lastCheck string
+	snapshot  map[string]interface{} // Normalized playbyplay (without plays) from the previous update
+	score     string                 // Scoreboard update (JSON) from the previous update
+	boxScore  string                 // Box score (JSON) from the previous update
 }
 
 func (s *Server) watchGame(sport *sports.Sport, game sports.ScheduledGame) {
-	ticker := time.NewTicker(gameLiveCheckDelay)
+	ticker := time.NewTicker(s.cadence.GameLiveCheck)
 	gameStatus := internalGameStatus {
-		state:         sports.Preview,
-		lastCheck:     (*sport).DefaultTimeString(),
-		currentPeriod: 1,
+		state:     sports.Preview,
+		lastCheck: (*sport).DefaultTimeString(),
 	}
 	for {
 		<-ticker.C
 		gameStatus = s.parseGame(sport, game, gameStatus)
 		if gameStatus.state == sports.Complete { // ScheduledGame is over, no need to watch
 			log.Debugf("Game complete (%s: %s)", (*sport).Name(), game.Id)
+			s.recordCompletedGame(*sport, game)
+			s.removeLatestPlayByPlay(*sport, game.Id)
+			go s.refreshStandings(sport)
 			break
 		}
 	}
 }
 
 func (s *Server) parseGame(sport *sports.Sport, game sports.ScheduledGame, prevGameStatus internalGameStatus) internalGameStatus {
-	channel := s.gameChannels[fmt.Sprintf(gameChannelStringFormat, (*sport).Name(), game.Id)]
+	gameString := fmt.Sprintf(gameChannelStringFormat, (*sport).Name(), game.Id)
+	channel := s.gameChannels[gameString]
 	values := url.Values{}
 	values.Add("gameId", game.Id)
 	values.Add("date", prevGameStatus.lastCheck)
-	values.Add("period", strconv.Itoa(prevGameStatus.currentPeriod))
+	values.Add("gameDate", sports.CreateDetailedStringFromDate(game.StartTime))
 	playbyplay := (*sport).PlayByPlay(values)
-	if playbyplay == nil { // Should never be nil
-		prevGameStatus.state = sports.Preview
+	if playbyplay == nil { // Upstream is unavailable
+		return prevGameStatus
+	}
+	if status := sports.UpstreamStatus(playbyplay); status < 200 || status >= 300 { // Failed poll, not an update
+		log.Errorf("Error polling %s game %s, upstream returned %d", (*sport).Name(), game.Id, status)
 		return prevGameStatus
 	}
 	// Debugging information
@@ -124,24 +232,107 @@ func (s *Server) parseGame(sport *sports.Sport, game sports.ScheduledGame, prevG
 	prevGameStatus.lastCheck = playbyplay["metadata"].(map[string]interface{})["lastCheck"].(string)
 	state, _ := playbyplay["metadata"].(map[string]interface{})["state"]
 	prevGameStatus.state = state.(sports.ScheduleState)
-	period, ok := playbyplay["game"].(map[string]interface{})["status"].(map[string]interface{})["period"]
-	if ok {
-		prevGameStatus.currentPeriod = period.(int)
-	}
 	//log.Debugf("Length of plays: %d", len(playbyplay["plays"].([]map[string]interface{})))
+	snapshot := normalizeSnapshot(playbyplay)
+	patch := createMergePatch(prevGameStatus.snapshot, snapshot)
+	prevGameStatus.snapshot = snapshot
+	plays, ok := playbyplay["plays"].([]map[string]interface{})
+	if !ok {
+		plays = make([]map[string]interface{}, 0)
+	}
+	if len(patch) == 0 && len(plays) == 0 { // Nothing changed since the previous update
+		return prevGameStatus
+	}
+	s.updateLatestPlayByPlay(gameString, playbyplay)
+	updateTime := time.Now()
 	var message websocket.Message
 	if prevGameStatus.state != sports.Intermission {
 		message = websocket.Message{
-			Type:     fmt.Sprintf("playbyplay update at %s", time.Now()),
+			Type:     fmt.Sprintf("playbyplay update at %s", updateTime),
 			Contents: playbyplay,
 		}
 	} else {
 		message = websocket.Message{
-			Type:     fmt.Sprintf("playbyplay update at %s", time.Now()),
+			Type:     fmt.Sprintf("playbyplay update at %s", updateTime),
 			Contents: map[string]interface{}{"contents": "intermission"},
 		}
 	}
-	*channel<-message
+	message.Patch = &websocket.Message{
+		Type:     fmt.Sprintf("playbyplay patch at %s", updateTime),
+		Contents: map[string]interface{}{"patch": patch, "plays": plays},
+	}
+	s.send(sport, game, channel, PlayByPlayMessage, message)
+	if events, ok := playbyplay["events"].([]map[string]interface{}); ok {
+		for _, event := range events {
+			s.send(sport, game, channel, EventMessage, websocket.Message{
+				Type:     string(event["type"].(sports.EventType)),
+				Contents: event,
+			})
+		}
+	}
+	prevGameStatus.score = s.pushScore(sport, game, playbyplay, prevGameStatus.score)
+	prevGameStatus.boxScore = s.pushBoxScore(sport, game, channel, prevGameStatus.boxScore)
 	//s.databaseServer.GetDatabase((*sport).Name()).Collection(strconv.Itoa(gameId)).InsertGameSnapshot(context.Background(), playbyplay)
 	return prevGameStatus
 }
+
+// pushScore sends the score, clock and state of game to the sport's score channel if they changed since previous (JSON),
+// returning the update
+func (s *Server) pushScore(sport *sports.Sport, game sports.ScheduledGame, playbyplay map[string]interface{}, previous string) string {
+	update := sports.BuildScoreboardUpdate(*sport, game.Id, playbyplay)
+	b, _ := json.Marshal(update)
+	if string(b) == previous {
+		return previous
+	}
+	s.send(sport, game, s.scoreChannels[(*sport).Name()], ScoreMessage, websocket.Message{
+		Type:     fmt.Sprintf("scoreboard update at %s", time.Now()),
+		Contents: update,
+	})
+	return string(b)
+}
+
+// pushBoxScore sends the box score of game to channel if it changed since previous (JSON), returning the box score
+func (s *Server) pushBoxScore(sport *sports.Sport, game sports.ScheduledGame, channel *chan websocket.Message, previous string) string {
+	if !sports.SupportsBoxScore(*sport) {
+		return previous
+	}
+	values := url.Values{}
+	values.Add("gameId", game.Id)
+	values.Add("gameDate", sports.CreateDetailedStringFromDate(game.StartTime))
+	boxScore := (*sport).(sports.BoxScorer).BoxScore(values)
+	if status := sports.UpstreamStatus(boxScore); boxScore == nil || status < 200 || status >= 300 {
+		return previous
+	}
+	b, _ := json.Marshal(map[string]interface{}{"home": boxScore["home"], "away": boxScore["away"]})
+	if string(b) == previous {
+		return previous
+	}
+	s.send(sport, game, channel, BoxScoreMessage, websocket.Message{
+		Type:     fmt.Sprintf("boxscore update at %s", time.Now()),
+		Contents: boxScore,
+	})
+	return string(b)
+}
+
+// send writes message (of kind) about game to channel and passes it to every listener
+func (s *Server) send(sport *sports.Sport, game sports.ScheduledGame, channel *chan websocket.Message, kind string, message websocket.Message) {
+	*channel <- message
+	s.listenersMutex.RLock()
+	defer s.listenersMutex.RUnlock()
+	for _, listener := range s.listeners {
+		listener.GameMessage(*sport, game, kind, message)
+	}
+}
+
+// Goroutine function
+// refreshStandings requests the standings of sport again after a game goes final, replacing the cached standings
+func (s *Server) refreshStandings(sport *sports.Sport) {
+	if !sports.SupportsStandings(*sport) {
+		return
+	}
+	for _, delay := range s.cadence.StandingsRefresh {
+		<-time.After(delay)
+		sports.InvalidateStandings(*sport)
+		(*sport).(sports.Ranker).Standings(nil)
+	}
+}
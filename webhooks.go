@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/henrymxu/gosports/database"
+	"github.com/henrymxu/gosports/webhooks"
+	"github.com/ngaut/log"
+	"net/http"
+)
+
+func (s *server) handleListWebhooks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.webhooks.Subscriptions())
+	}
+}
+
+func (s *server) handleCreateWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		subscription := webhooks.Subscription{}
+		if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+			logHttpError(w, &httpError{
+				http.StatusBadRequest,
+				"Invalid webhook body",
+			})
+			return
+		}
+		if err := s.webhooks.Create(&subscription); err == webhooks.ErrInvalidSubscription {
+			logHttpError(w, &httpError{
+				http.StatusBadRequest,
+				"Webhooks require an http(s) url and valid events",
+			})
+			return
+		} else if err != nil {
+			log.Errorf("Error storing webhook: %s", err)
+			logHttpError(w, &httpError{
+				http.StatusInternalServerError,
+				"Could not store webhook",
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(subscription)
+	}
+}
+
+func (s *server) handleDeleteWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.webhooks.Delete(mux.Vars(r)["id"]); err != nil {
+			log.Errorf("Error deleting webhook: %s", err)
+			logHttpError(w, &httpError{
+				http.StatusInternalServerError,
+				"Could not delete webhook",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleListDeliveries returns the recent webhook deliveries and the dead letters, filtered by the status query
+func (s *server) handleListDeliveries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deliveries, err := s.webhooks.Deliveries(webhooks.DeliveryStatus(r.URL.Query().Get("status")))
+		if err != nil {
+			log.Errorf("Error retrieving webhook deliveries: %s", err)
+			logHttpError(w, &httpError{
+				http.StatusInternalServerError,
+				"Could not retrieve webhook deliveries",
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(deliveries)
+	}
+}
+
+func (s *server) handleReplayDelivery() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		delivery, err := s.webhooks.Replay(mux.Vars(r)["id"])
+		switch err {
+		case nil:
+		case database.ErrNotFound:
+			logHttpError(w, &httpError{
+				http.StatusNotFound,
+				"Webhook delivery not found",
+			})
+			return
+		case webhooks.ErrDeliveryPending:
+			logHttpError(w, &httpError{
+				http.StatusConflict,
+				"Webhook delivery is still being attempted",
+			})
+			return
+		case webhooks.ErrQueueFull:
+			logHttpError(w, &httpError{
+				http.StatusServiceUnavailable,
+				"Webhook delivery queue is full",
+			})
+			return
+		default:
+			log.Errorf("Error replaying webhook delivery: %s", err)
+			logHttpError(w, &httpError{
+				http.StatusInternalServerError,
+				"Could not replay webhook delivery",
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(delivery)
+	}
+}
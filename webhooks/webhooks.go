@@ -0,0 +1,533 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/henrymxu/gosports/database"
+	"github.com/henrymxu/gosports/sports"
+	"github.com/henrymxu/gosports/watch"
+	"github.com/henrymxu/gosports/websocket"
+	"github.com/ngaut/log"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SubscriptionsCollectionName = "webhooks"
+const DeadLettersCollectionName = "webhookDeadLetters"
+
+const maxAttempts = 6
+const queueSize = 1000
+const workers = 4
+const maxRecentDeliveries = 500 // Deliveries kept in memory, dead letters are also stored in the database
+
+// Headers of every delivery, the signature is `sha256=<hex>`, see Sign
+const (
+	SignatureHeader = "X-GoSports-Signature"
+	TimestampHeader = "X-GoSports-Timestamp"
+	EventHeader     = "X-GoSports-Event"
+	DeliveryHeader  = "X-GoSports-Delivery"
+)
+
+var minRetryDelay = 1 * time.Second // Doubled after every failed attempt
+
+var ErrInvalidSubscription = errors.New("invalid subscription")
+var ErrQueueFull = errors.New("delivery queue is full")
+var ErrDeliveryPending = errors.New("delivery is pending")
+
+type EventType string
+
+const (
+	GameStart   EventType = "gameStart"
+	ScoreChange EventType = "scoreChange"
+	PeriodEnd   EventType = "periodEnd"
+	GameFinal   EventType = "gameFinal"
+)
+
+var eventTypes = []EventType{GameStart, ScoreChange, PeriodEnd, GameFinal}
+
+// Subscription receives the events of the games that match every one of its filters, an empty filter matches everything
+type Subscription struct {
+	Id     string      `bson:"_id" json:"id"`
+	URL    string      `bson:"url" json:"url"`
+	Secret string      `bson:"secret" json:"secret,omitempty"` // Signs deliveries, generated and only returned when created
+	Sports []string    `bson:"sports" json:"sports,omitempty"`
+	Teams  []string    `bson:"teams" json:"teams,omitempty"` // Stable team ids (nhl-10)
+	Games  []string    `bson:"games" json:"games,omitempty"`
+	Events []EventType `bson:"events" json:"events,omitempty"`
+}
+
+type DeliveryStatus string
+
+const (
+	Pending   DeliveryStatus = "pending"
+	Delivered DeliveryStatus = "delivered"
+	Dead      DeliveryStatus = "dead" // Every attempt failed, the delivery is in the dead-letter log until it is replayed
+)
+
+// Delivery is an event sent to a subscription, Id is sent with every attempt so subscribers can ignore duplicates
+type Delivery struct {
+	Id             string         `bson:"_id" json:"id"`
+	SubscriptionId string         `bson:"subscriptionId" json:"subscriptionId"`
+	URL            string         `bson:"url" json:"url"`
+	Event          EventType      `bson:"event" json:"event"`
+	Payload        string         `bson:"payload" json:"payload"` // JSON body
+	Status         DeliveryStatus `bson:"status" json:"status"`
+	Attempts       int            `bson:"attempts" json:"attempts"`
+	LastStatusCode int            `bson:"lastStatusCode" json:"lastStatusCode"` // 0 if the request failed
+	LastError      string         `bson:"lastError" json:"lastError,omitempty"`
+	Created        time.Time      `bson:"created" json:"created"`
+	Updated        time.Time      `bson:"updated" json:"updated"`
+	deadLettered   bool           // Stored in the dead-letter log
+}
+
+// Webhooks delivers game events to subscriptions, retrying failed deliveries with exponential backoff.
+// Subscriptions and deliveries that failed every attempt (dead letters) are stored in the database.
+type Webhooks struct {
+	subscriptionsCollection database.Collection
+	deadLettersCollection   database.Collection
+	client                  *http.Client
+	subscriptions           map[string]*Subscription
+	deliveries              map[string]*Delivery // Recent deliveries by id
+	recent                  []string             // Ids of recent deliveries, oldest first
+	queue                   chan *Delivery
+	overflow                []*Delivery   // Deliveries that did not fit in the queue, dead-lettered by the workers
+	overflowed              chan struct{} // Signals the workers that overflow is not empty
+	mutex                   sync.Mutex
+}
+
+// CreateWebhooks loads the subscriptions stored in subscriptions and starts delivering with client
+func CreateWebhooks(subscriptions database.Collection, deadLetters database.Collection, client *http.Client) *Webhooks {
+	webhooks := &Webhooks{
+		subscriptionsCollection: subscriptions,
+		deadLettersCollection:   deadLetters,
+		client:                  client,
+		subscriptions:           make(map[string]*Subscription),
+		deliveries:              make(map[string]*Delivery),
+		queue:                   make(chan *Delivery, queueSize),
+		overflowed:              make(chan struct{}, 1),
+	}
+	var stored []Subscription
+	if err := subscriptions.FindDocuments(context.Background(), &stored); err != nil {
+		log.Errorf("Error loading webhook subscriptions: %s", err)
+	}
+	for i := range stored {
+		webhooks.subscriptions[stored[i].Id] = &stored[i]
+	}
+	for i := 0; i < workers; i++ {
+		go webhooks.deliver()
+	}
+	return webhooks
+}
+
+// Sign returns the hex HMAC-SHA256 of `timestamp.payload` with secret, subscribers compare it to the signature header
+func Sign(secret string, timestamp string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = io.WriteString(mac, timestamp+"."+payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Subscriptions returns every subscription, without secrets
+func (w *Webhooks) Subscriptions() []Subscription {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	subscriptions := make([]Subscription, 0, len(w.subscriptions))
+	for _, subscription := range w.subscriptions {
+		copied := *subscription
+		copied.Secret = ""
+		subscriptions = append(subscriptions, copied)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].Id < subscriptions[j].Id
+	})
+	return subscriptions
+}
+
+// Create validates subscription, generates its id and secret, and stores it.
+// Returns ErrInvalidSubscription if its url or events are invalid.
+func (w *Webhooks) Create(subscription *Subscription) error {
+	parsed, err := url.Parse(subscription.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ErrInvalidSubscription
+	}
+	for _, event := range subscription.Events {
+		if !validEventType(event) {
+			return ErrInvalidSubscription
+		}
+	}
+	subscription.Id = generateId()
+	subscription.Secret = generateId() + generateId()
+	if err := w.subscriptionsCollection.ReplaceDocument(context.Background(), subscription.Id, subscription); err != nil {
+		return err
+	}
+	stored := *subscription
+	w.mutex.Lock()
+	w.subscriptions[stored.Id] = &stored
+	w.mutex.Unlock()
+	return nil
+}
+
+// Delete removes the subscription with id, its pending deliveries are dead-lettered
+func (w *Webhooks) Delete(id string) error {
+	if err := w.subscriptionsCollection.DeleteDocument(context.Background(), id); err != nil {
+		return err
+	}
+	w.mutex.Lock()
+	delete(w.subscriptions, id)
+	w.mutex.Unlock()
+	return nil
+}
+
+// Deliveries returns the recent deliveries and the dead letters with status (every status if empty), most recent first
+func (w *Webhooks) Deliveries(status DeliveryStatus) ([]Delivery, error) {
+	w.mutex.Lock()
+	deliveries := make([]Delivery, 0, len(w.recent))
+	for _, id := range w.recent {
+		if delivery := w.deliveries[id]; status == "" || delivery.Status == status {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	w.mutex.Unlock()
+	if status == "" || status == Dead {
+		var deadLetters []Delivery
+		if err := w.deadLettersCollection.FindDocuments(context.Background(), &deadLetters); err != nil {
+			return nil, err
+		}
+		for _, deadLetter := range deadLetters {
+			if w.recentDelivery(deadLetter.Id) == nil {
+				deliveries = append(deliveries, deadLetter)
+			}
+		}
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].Created.After(deliveries[j].Created)
+	})
+	return deliveries, nil
+}
+
+// Replay sends the delivery with id again, with the same id and payload.
+// Returns database.ErrNotFound if it is neither a recent delivery nor a dead letter,
+// and ErrDeliveryPending if it is still being attempted.
+func (w *Webhooks) Replay(id string) (*Delivery, error) {
+	delivery := w.recentDelivery(id)
+	if delivery == nil {
+		delivery = &Delivery{}
+		if err := w.deadLettersCollection.FindDocument(context.Background(), id, delivery); err != nil {
+			return nil, err
+		}
+		delivery.deadLettered = true
+		w.record(delivery)
+	}
+	w.mutex.Lock()
+	if delivery.Status == Pending {
+		w.mutex.Unlock()
+		return nil, ErrDeliveryPending
+	}
+	previous := *delivery
+	delivery.Status = Pending
+	delivery.Attempts = 0
+	delivery.Updated = time.Now()
+	copied := *delivery
+	w.mutex.Unlock()
+	if !w.enqueue(delivery) {
+		w.mutex.Lock()
+		delivery.Status, delivery.Attempts, delivery.Updated = previous.Status, previous.Attempts, previous.Updated
+		w.mutex.Unlock()
+		return nil, ErrQueueFull
+	}
+	return &copied, nil
+}
+
+// GameMessage creates a delivery for every subscription that matches an event the watcher sent to a game's channel.
+// Other messages and events that are not subscribable are ignored.
+func (w *Webhooks) GameMessage(sport sports.Sport, game sports.ScheduledGame, kind string, message websocket.Message) {
+	event, ok := eventTypeOf(message)
+	if kind != watch.EventMessage || !ok {
+		return
+	}
+	teams := make([]string, 0, 2)
+	teamSummaries := make(map[string]interface{})
+	for _, side := range []string{"home", "away"} {
+		team, _ := game.Details[side].(map[string]interface{})
+		teams = append(teams, fmt.Sprint(team["id"]))
+		teamSummaries[side] = map[string]interface{}{"id": team["id"], "name": team["name"], "abbr": team["abbr"]}
+	}
+	w.mutex.Lock()
+	subscriptions := make([]*Subscription, 0)
+	for _, subscription := range w.subscriptions {
+		if subscription.matches(sport.Name(), game.Id, teams, event) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	w.mutex.Unlock()
+	for _, subscription := range subscriptions {
+		now := time.Now()
+		delivery := &Delivery{
+			Id:             generateId(),
+			SubscriptionId: subscription.Id,
+			URL:            subscription.URL,
+			Event:          event,
+			Status:         Pending,
+			Created:        now,
+			Updated:        now,
+		}
+		payload, err := json.Marshal(map[string]interface{}{
+			"id":        delivery.Id,
+			"event":     event,
+			"sport":     sport.Name(),
+			"gameId":    game.Id,
+			"home":      teamSummaries["home"],
+			"away":      teamSummaries["away"],
+			"data":      message.Contents,
+			"timestamp": now.Unix(),
+		})
+		if err != nil {
+			log.Errorf("Error encoding webhook payload: %s", err)
+			continue
+		}
+		delivery.Payload = string(payload)
+		w.record(delivery)
+		if !w.enqueue(delivery) {
+			w.addOverflow(delivery)
+		}
+	}
+}
+
+func (s *Subscription) matches(sport string, gameId string, teams []string, event EventType) bool {
+	if !contains(s.Sports, sport) || !contains(s.Games, gameId) {
+		return false
+	}
+	if len(s.Events) > 0 {
+		found := false
+		for _, subscribed := range s.Events {
+			found = found || subscribed == event
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(s.Teams) == 0 {
+		return true
+	}
+	for _, team := range teams {
+		if contains(s.Teams, team) {
+			return true
+		}
+	}
+	return false
+}
+
+// eventTypeOf maps an event message of a game's channel to the event type subscriptions receive
+func eventTypeOf(message websocket.Message) (EventType, bool) {
+	switch sports.EventType(message.Type) {
+	case sports.PeriodStart:
+		if period, ok := message.Contents["period"].(int); ok && period == 1 {
+			return GameStart, true
+		}
+	case sports.ScoreChange:
+		return ScoreChange, true
+	case sports.PeriodEnd:
+		return PeriodEnd, true
+	case sports.GameFinal:
+		return GameFinal, true
+	}
+	return "", false
+}
+
+// Goroutine function
+// deliver attempts the deliveries in the queue and dead-letters the deliveries that did not fit in it
+func (w *Webhooks) deliver() {
+	for {
+		select {
+		case delivery := <-w.queue:
+			w.attempt(delivery)
+		case <-w.overflowed:
+			w.mutex.Lock()
+			overflow := w.overflow
+			w.overflow = nil
+			w.mutex.Unlock()
+			for _, delivery := range overflow {
+				w.finish(delivery, Dead, 0, ErrQueueFull.Error())
+			}
+		}
+	}
+}
+
+// addOverflow hands delivery to the workers to be dead-lettered, so the watcher never waits for the database
+func (w *Webhooks) addOverflow(delivery *Delivery) {
+	w.mutex.Lock()
+	w.overflow = append(w.overflow, delivery)
+	w.mutex.Unlock()
+	select {
+	case w.overflowed <- struct{}{}:
+	default: // The workers were already signaled
+	}
+}
+
+// attempt sends delivery to its subscription, scheduling a retry if it fails and attempts remain
+func (w *Webhooks) attempt(delivery *Delivery) {
+	w.mutex.Lock()
+	subscription, ok := w.subscriptions[delivery.SubscriptionId]
+	var secret string
+	if ok {
+		secret = subscription.Secret
+	}
+	payload := delivery.Payload
+	w.mutex.Unlock()
+	if !ok {
+		w.finish(delivery, Dead, 0, "subscription was deleted")
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request, err := http.NewRequest(http.MethodPost, delivery.URL, strings.NewReader(payload))
+	if err != nil {
+		w.finish(delivery, Dead, 0, err.Error())
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(SignatureHeader, "sha256="+Sign(secret, timestamp, payload))
+	request.Header.Set(EventHeader, string(delivery.Event))
+	request.Header.Set(DeliveryHeader, delivery.Id)
+	status, errorText := 0, ""
+	response, err := w.client.Do(request)
+	if err != nil {
+		errorText = err.Error()
+	} else {
+		status = response.StatusCode
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		_ = response.Body.Close()
+		if status < 200 || status >= 300 {
+			errorText = fmt.Sprintf("subscriber returned %d", status)
+		}
+	}
+	if errorText == "" {
+		w.finish(delivery, Delivered, status, "")
+		return
+	}
+	w.mutex.Lock()
+	attempts := delivery.Attempts + 1
+	w.mutex.Unlock()
+	if attempts >= maxAttempts || !retryable(status, err) {
+		w.finish(delivery, Dead, status, errorText)
+		return
+	}
+	w.update(delivery, Pending, status, errorText)
+	delay := minRetryDelay << uint(attempts-1)
+	log.Debugf("Webhook delivery %s failed (%s), retrying in %s", delivery.Id, errorText, delay.String())
+	time.AfterFunc(delay, func() {
+		if !w.enqueue(delivery) {
+			w.finish(delivery, Dead, status, ErrQueueFull.Error())
+		}
+	})
+}
+
+// retryable checks if a failed attempt may succeed later, requests the subscriber rejected are not retried
+func retryable(status int, err error) bool {
+	return err != nil || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+// update records an attempt of delivery
+func (w *Webhooks) update(delivery *Delivery, status DeliveryStatus, statusCode int, errorText string) Delivery {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	delivery.Status = status
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = errorText
+	delivery.Updated = time.Now()
+	return *delivery
+}
+
+// finish records the last attempt of delivery, storing it in the dead-letter log if it is dead
+// and removing it from the log once it is delivered
+func (w *Webhooks) finish(delivery *Delivery, status DeliveryStatus, statusCode int, errorText string) {
+	finished := w.update(delivery, status, statusCode, errorText)
+	var err error
+	if status == Dead {
+		log.Errorf("Webhook delivery %s to %s failed after %d attempts: %s", finished.Id, finished.URL, finished.Attempts, errorText)
+		err = w.deadLettersCollection.ReplaceDocument(context.Background(), finished.Id, &finished)
+		w.setDeadLettered(delivery, err == nil)
+	} else if finished.deadLettered {
+		err = w.deadLettersCollection.DeleteDocument(context.Background(), finished.Id)
+		w.setDeadLettered(delivery, err != nil)
+	}
+	if err != nil {
+		log.Errorf("Error updating webhook dead letter %s: %s", finished.Id, err)
+	}
+}
+
+func (w *Webhooks) setDeadLettered(delivery *Delivery, deadLettered bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	delivery.deadLettered = deadLettered
+}
+
+func (w *Webhooks) enqueue(delivery *Delivery) bool {
+	select {
+	case w.queue <- delivery:
+		return true
+	default:
+		return false
+	}
+}
+
+// record adds delivery to the recent deliveries, dropping the oldest past maxRecentDeliveries
+func (w *Webhooks) record(delivery *Delivery) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if _, ok := w.deliveries[delivery.Id]; ok {
+		return
+	}
+	if len(w.recent) == maxRecentDeliveries {
+		delete(w.deliveries, w.recent[0])
+		w.recent = w.recent[1:]
+	}
+	w.deliveries[delivery.Id] = delivery
+	w.recent = append(w.recent, delivery.Id)
+}
+
+func (w *Webhooks) recentDelivery(id string) *Delivery {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.deliveries[id]
+}
+
+func validEventType(event EventType) bool {
+	for _, eventType := range eventTypes {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func generateId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,326 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/henrymxu/gosports/database"
+	"github.com/henrymxu/gosports/sports"
+	"github.com/henrymxu/gosports/watch"
+	"github.com/henrymxu/gosports/websocket"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryCollection is a database.Collection that keeps its documents as JSON
+type memoryCollection struct {
+	documents map[string][]byte
+	mutex     sync.Mutex
+}
+
+func createMemoryCollection() *memoryCollection {
+	return &memoryCollection{documents: make(map[string][]byte)}
+}
+
+func (c *memoryCollection) InsertGameSnapshot(ctx context.Context, snapshot interface{}) {}
+
+func (c *memoryCollection) WatchGame(ctx context.Context) (database.Cursor, error) {
+	return nil, nil
+}
+
+func (c *memoryCollection) FindDocument(ctx context.Context, id string, document interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	encoded, ok := c.documents[id]
+	if !ok {
+		return database.ErrNotFound
+	}
+	return json.Unmarshal(encoded, document)
+}
+
+func (c *memoryCollection) FindDocuments(ctx context.Context, documents interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	encoded := make([]json.RawMessage, 0, len(c.documents))
+	for _, document := range c.documents {
+		encoded = append(encoded, document)
+	}
+	b, _ := json.Marshal(encoded)
+	return json.Unmarshal(b, documents)
+}
+
+func (c *memoryCollection) ReplaceDocument(ctx context.Context, id string, document interface{}) error {
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.documents[id] = encoded
+	return nil
+}
+
+func (c *memoryCollection) DeleteDocument(ctx context.Context, id string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.documents, id)
+	return nil
+}
+
+func (c *memoryCollection) contains(id string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, ok := c.documents[id]
+	return ok
+}
+
+type testSport struct{}
+
+func (testSport) Name() string                                        { return "nhl" }
+func (testSport) Schedule(params url.Values) map[string]interface{}   { return nil }
+func (testSport) PlayByPlay(params url.Values) map[string]interface{} { return nil }
+func (testSport) ParseScheduleState(statusCode int) sports.ScheduleState {
+	return sports.Preview
+}
+func (testSport) DefaultTimeString() string { return "" }
+
+// subscriber is an httptest.Server that answers deliveries with the next status of statuses (the last one once they run out)
+type subscriber struct {
+	*httptest.Server
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+	times    []time.Time
+	mutex    sync.Mutex
+}
+
+func createSubscriber(statuses ...int) *subscriber {
+	s := &subscriber{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		s.mutex.Lock()
+		status := s.statuses[0]
+		if len(s.statuses) > 1 {
+			s.statuses = s.statuses[1:]
+		}
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, string(body))
+		s.times = append(s.times, time.Now())
+		s.mutex.Unlock()
+		w.WriteHeader(status)
+	}))
+	return s
+}
+
+func (s *subscriber) setStatuses(statuses ...int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.statuses = statuses
+}
+
+func (s *subscriber) count() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.requests)
+}
+
+// createTestWebhooks creates webhooks with a subscription to every event of the subscriber, retrying quickly
+func createTestWebhooks(t *testing.T, target *subscriber) (*Webhooks, *Subscription, *memoryCollection) {
+	minRetryDelay = 10 * time.Millisecond
+	deadLetters := createMemoryCollection()
+	w := CreateWebhooks(createMemoryCollection(), deadLetters, target.Client())
+	subscription := &Subscription{URL: target.URL}
+	if err := w.Create(subscription); err != nil {
+		t.Fatalf("Error creating subscription: %s", err)
+	}
+	return w, subscription, deadLetters
+}
+
+// sendScoreChange sends a score change of a game to w and returns the id of its delivery
+func sendScoreChange(t *testing.T, w *Webhooks) string {
+	game := sports.ScheduledGame{
+		Id: "2019020001",
+		Details: map[string]interface{}{
+			"home": map[string]interface{}{"id": "nhl-10", "name": "Toronto Maple Leafs", "abbr": "TOR"},
+			"away": map[string]interface{}{"id": "nhl-8", "name": "Montréal Canadiens", "abbr": "MTL"},
+		},
+	}
+	message := websocket.Message{Type: string(sports.ScoreChange), Contents: map[string]interface{}{"period": 1}}
+	w.GameMessage(testSport{}, game, watch.EventMessage, message)
+	deliveries, _ := w.Deliveries("")
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected 1 delivery, got %d", len(deliveries))
+	}
+	return deliveries[0].Id
+}
+
+// waitForStatus waits for the delivery with id to have status and returns it
+func waitForStatus(t *testing.T, w *Webhooks, id string, status DeliveryStatus) Delivery {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if delivery := w.recentDelivery(id); delivery != nil {
+			w.mutex.Lock()
+			copied := *delivery
+			w.mutex.Unlock()
+			if copied.Status == status {
+				return copied
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Delivery %s did not become %s", id, status)
+	return Delivery{}
+}
+
+// waitForDeadLetter waits for the delivery with id to be stored in (or removed from) deadLetters,
+// which happens after its status is updated
+func waitForDeadLetter(t *testing.T, w *Webhooks, deadLetters *memoryCollection, id string, stored bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for deadLetters.contains(id) != stored || isDeadLettered(w, id) != stored {
+		if time.Now().After(deadline) {
+			t.Fatalf("Dead letter %s stored: expected %t", id, stored)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func isDeadLettered(w *Webhooks, id string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	delivery, ok := w.deliveries[id]
+	return ok && delivery.deadLettered
+}
+
+func TestDeliveryHeaders(t *testing.T) {
+	target := createSubscriber(http.StatusOK)
+	defer target.Close()
+	w, subscription, _ := createTestWebhooks(t, target)
+	id := sendScoreChange(t, w)
+	waitForStatus(t, w, id, Delivered)
+
+	request := target.requests[0]
+	timestamp := request.Header.Get(TimestampHeader)
+	if seconds, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(seconds, 0)) > time.Minute {
+		t.Errorf("Invalid timestamp header %q", timestamp)
+	}
+	expected := "sha256=" + Sign(subscription.Secret, timestamp, target.bodies[0])
+	if signature := request.Header.Get(SignatureHeader); signature != expected {
+		t.Errorf("Expected signature %s, got %s", expected, signature)
+	}
+	if event := request.Header.Get(EventHeader); event != string(ScoreChange) {
+		t.Errorf("Expected event header %s, got %s", ScoreChange, event)
+	}
+	if delivery := request.Header.Get(DeliveryHeader); delivery != id {
+		t.Errorf("Expected delivery header %s, got %s", id, delivery)
+	}
+	if !strings.Contains(target.bodies[0], `"event":"scoreChange"`) {
+		t.Errorf("Unexpected payload %s", target.bodies[0])
+	}
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	target := createSubscriber(http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK)
+	defer target.Close()
+	w, _, _ := createTestWebhooks(t, target)
+	id := sendScoreChange(t, w)
+	delivery := waitForStatus(t, w, id, Delivered)
+
+	if delivery.Attempts != 3 || target.count() != 3 {
+		t.Fatalf("Expected 3 attempts, got %d (%d requests)", delivery.Attempts, target.count())
+	}
+	first, second := target.times[1].Sub(target.times[0]), target.times[2].Sub(target.times[1])
+	if first < minRetryDelay || second < 2*minRetryDelay {
+		t.Errorf("Expected retries after %s and %s, got %s and %s", minRetryDelay, 2*minRetryDelay, first, second)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	target := createSubscriber(http.StatusBadRequest)
+	defer target.Close()
+	w, _, deadLetters := createTestWebhooks(t, target)
+	id := sendScoreChange(t, w)
+	delivery := waitForStatus(t, w, id, Dead)
+
+	time.Sleep(5 * minRetryDelay)
+	if delivery.Attempts != 1 || target.count() != 1 {
+		t.Errorf("Expected 1 attempt, got %d (%d requests)", delivery.Attempts, target.count())
+	}
+	if delivery.LastStatusCode != http.StatusBadRequest {
+		t.Errorf("Expected last status %d, got %d", http.StatusBadRequest, delivery.LastStatusCode)
+	}
+	waitForDeadLetter(t, w, deadLetters, id, true)
+}
+
+func TestDeadLetterAfterAttempts(t *testing.T) {
+	target := createSubscriber(http.StatusInternalServerError)
+	defer target.Close()
+	w, _, deadLetters := createTestWebhooks(t, target)
+	id := sendScoreChange(t, w)
+	delivery := waitForStatus(t, w, id, Dead)
+	waitForDeadLetter(t, w, deadLetters, id, true)
+
+	if delivery.Attempts != maxAttempts || target.count() != maxAttempts {
+		t.Errorf("Expected %d attempts, got %d (%d requests)", maxAttempts, delivery.Attempts, target.count())
+	}
+	stored := Delivery{}
+	if err := deadLetters.FindDocument(context.Background(), id, &stored); err != nil {
+		t.Fatalf("Delivery %s was not dead-lettered: %s", id, err)
+	}
+	if stored.Status != Dead || stored.Attempts != maxAttempts {
+		t.Errorf("Unexpected dead letter %+v", stored)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	target := createSubscriber(http.StatusBadRequest)
+	defer target.Close()
+	w, _, deadLetters := createTestWebhooks(t, target)
+	id := sendScoreChange(t, w)
+	waitForStatus(t, w, id, Dead)
+	waitForDeadLetter(t, w, deadLetters, id, true)
+
+	target.setStatuses(http.StatusOK)
+	if _, err := w.Replay(id); err != nil {
+		t.Fatalf("Error replaying delivery %s: %s", id, err)
+	}
+	delivery := waitForStatus(t, w, id, Delivered)
+	if delivery.Attempts != 1 {
+		t.Errorf("Expected 1 attempt after the replay, got %d", delivery.Attempts)
+	}
+	if target.count() != 2 || target.bodies[1] != target.bodies[0] {
+		t.Errorf("Expected the replay to send the same payload")
+	}
+	if header := target.requests[1].Header.Get(DeliveryHeader); header != id {
+		t.Errorf("Expected delivery header %s, got %s", id, header)
+	}
+	waitForDeadLetter(t, w, deadLetters, id, false)
+	if _, err := w.Replay("missing"); err != database.ErrNotFound {
+		t.Errorf("Expected %s replaying a missing delivery, got %v", database.ErrNotFound, err)
+	}
+}
+
+func TestReplayQueueFull(t *testing.T) {
+	deadLetters := createMemoryCollection()
+	w := &Webhooks{
+		deadLettersCollection: deadLetters,
+		deliveries:            make(map[string]*Delivery),
+		queue:                 make(chan *Delivery), // No workers, every enqueue fails
+	}
+	deadLetter := Delivery{Id: "dead", Status: Dead, Attempts: maxAttempts}
+	_ = deadLetters.ReplaceDocument(context.Background(), deadLetter.Id, &deadLetter)
+
+	for i := 0; i < 2; i++ { // A failed replay must not leave the delivery pending
+		if _, err := w.Replay(deadLetter.Id); err != ErrQueueFull {
+			t.Fatalf("Expected %s replaying with a full queue, got %v", ErrQueueFull, err)
+		}
+	}
+	if delivery := w.recentDelivery(deadLetter.Id); delivery.Status != Dead || delivery.Attempts != maxAttempts {
+		t.Errorf("Expected the delivery to stay dead after %d attempts, got %s after %d", maxAttempts, delivery.Status, delivery.Attempts)
+	}
+}
@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/henrymxu/gosports/proto"
+	"github.com/vmihailenco/msgpack/v5"
+	"net/http"
+	"strings"
+)
+
+const subprotocolPrefix = "gosports." // Example gosports.json or gosports.msgpack
+
+// Encoding serializes messages before they are written to clients
+type Encoding interface {
+	Name() string
+	FrameType() int // websocket.TextMessage or websocket.BinaryMessage
+	Encode(message Message) ([]byte, error)
+}
+
+type jsonEncoding struct{}
+
+type msgpackEncoding struct{}
+
+// protobufEncoding encodes messages as the gosports.Message protocol buffer defined in proto/gosports.proto,
+// with the typed model of their contents
+type protobufEncoding struct{}
+
+var encodings = []Encoding{jsonEncoding{}, msgpackEncoding{}, protobufEncoding{}}
+
+// GetEncoding returns the encoding with the provided name, or nil if it does not exist
+func GetEncoding(name string) Encoding {
+	for _, encoding := range encodings {
+		if encoding.Name() == name {
+			return encoding
+		}
+	}
+	return nil
+}
+
+// DefaultEncoding is used when a client does not negotiate an encoding
+func DefaultEncoding() Encoding {
+	return jsonEncoding{}
+}
+
+// subprotocols returns the websocket subprotocols offered by the server, in order of preference
+func subprotocols() []string {
+	result := make([]string, len(encodings))
+	for i, encoding := range encodings {
+		result[i] = subprotocolPrefix + encoding.Name()
+	}
+	return result
+}
+
+// subprotocolEncoding returns the encoding of the subprotocol the upgrade of r negotiates
+// (the server's most preferred subprotocol the client offers), or nil if the client offers none
+func subprotocolEncoding(r *http.Request) Encoding {
+	offered := websocket.Subprotocols(r)
+	for _, subprotocol := range subprotocols() {
+		for _, clientSubprotocol := range offered {
+			if clientSubprotocol == subprotocol {
+				return GetEncoding(strings.TrimPrefix(subprotocol, subprotocolPrefix))
+			}
+		}
+	}
+	return nil
+}
+
+// EncodingMatchesSubprotocol checks that the encoding query of r, if set, is the encoding of the negotiated subprotocol
+func EncodingMatchesSubprotocol(r *http.Request) bool {
+	query := r.URL.Query().Get("encoding")
+	negotiated := subprotocolEncoding(r)
+	return query == "" || negotiated == nil || negotiated.Name() == query
+}
+
+func (jsonEncoding) Name() string {
+	return "json"
+}
+
+func (jsonEncoding) FrameType() int {
+	return websocket.TextMessage
+}
+
+func (jsonEncoding) Encode(message Message) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func (msgpackEncoding) Name() string {
+	return "msgpack"
+}
+
+func (msgpackEncoding) FrameType() int {
+	return websocket.BinaryMessage
+}
+
+func (msgpackEncoding) Encode(message Message) ([]byte, error) {
+	return msgpack.Marshal(map[string]interface{}{
+		"Type":     message.Type,
+		"Id":       message.Id,
+		"Contents": message.Contents,
+	})
+}
+
+func (protobufEncoding) Name() string {
+	return "protobuf"
+}
+
+func (protobufEncoding) FrameType() int {
+	return websocket.BinaryMessage
+}
+
+func (protobufEncoding) Encode(message Message) ([]byte, error) {
+	return proto.MarshalMessage(message.Type, int64(message.Id), message.Contents)
+}
@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+)
+
+const historySize = 100 // Number of messages retained per write channel
+
+// History records the messages written to a write channel so that transports that
+// cannot hold a websocket open (long polling) can replay them from a cursor.
+type History struct {
+	mutex    sync.Mutex
+	messages []Message
+	next     int       // Cursor that will be assigned to the next appended message
+	updated  chan bool // Closed (and replaced) whenever a message is appended
+}
+
+func newHistory() *History {
+	return &History{
+		messages: make([]Message, 0, historySize),
+		updated:  make(chan bool),
+	}
+}
+
+// Append records a message and wakes up every waiting reader
+func (h *History) Append(message Message) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if len(h.messages) == historySize {
+		h.messages = append(h.messages[:0], h.messages[1:]...)
+	}
+	h.messages = append(h.messages, message)
+	h.next++
+	close(h.updated)
+	h.updated = make(chan bool)
+}
+
+// Cursor returns the cursor that will be assigned to the next appended message, to only read newer messages
+func (h *History) Cursor() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.next
+}
+
+// Since returns the retained messages at or after cursor, the cursor to use for the next call,
+// and a channel that is closed once a newer message is appended.
+// If cursor is older than the oldest retained message, all retained messages are returned.
+func (h *History) Since(cursor int) ([]Message, int, <-chan bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	first := h.next - len(h.messages)
+	if cursor < first {
+		cursor = first
+	}
+	if cursor > h.next {
+		cursor = h.next
+	}
+	messages := make([]Message, h.next-cursor)
+	copy(messages, h.messages[cursor-first:])
+	return messages, h.next, h.updated
+}
+
+// WaitForHistories blocks until at least one of the histories has messages past its cursor
+// or ctx is done. The returned slices are indexed the same as histories.
+func WaitForHistories(ctx context.Context, histories []*History, cursors []int) ([][]Message, []int) {
+	messages := make([][]Message, len(histories))
+	nextCursors := make([]int, len(histories))
+	for {
+		found := false
+		woken := make(chan bool, len(histories))
+		waitCtx, cancel := context.WithCancel(ctx)
+		for i, history := range histories {
+			var updated <-chan bool
+			messages[i], nextCursors[i], updated = history.Since(cursors[i])
+			if len(messages[i]) > 0 {
+				found = true
+			}
+			go func() {
+				select {
+				case <-updated:
+					woken <- true
+				case <-waitCtx.Done():
+				}
+			}()
+		}
+		if found {
+			cancel()
+			return messages, nextCursors
+		}
+		select {
+		case <-woken:
+			cancel()
+		case <-ctx.Done():
+			cancel()
+			return messages, nextCursors
+		}
+	}
+}
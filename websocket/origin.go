@@ -0,0 +1,59 @@
+package websocket
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OriginPolicy decides which request origins may open websockets and receive CORS headers
+type OriginPolicy struct {
+	Origins          []string // Exact (https://example.com), wildcard subdomain (https://*.example.com) or any (*) origins
+	AllowEmptyOrigin bool     // Allow requests without an Origin header, such as native apps
+	AllowSameHost    bool     // Allow origins whose host is the host the request was sent to, regardless of scheme
+}
+
+// Allowed checks the Origin header of a request against the policy
+func (p *OriginPolicy) Allowed(r *http.Request) bool {
+	return p.AllowedOrigin(r.Header.Get("Origin"), r.Host)
+}
+
+// AllowedOrigin checks an origin sent to host against the policy
+func (p *OriginPolicy) AllowedOrigin(origin string, host string) bool {
+	if origin == "" {
+		return p.AllowEmptyOrigin
+	}
+	originUrl, err := url.Parse(origin)
+	if err != nil || originUrl.Host == "" {
+		return false
+	}
+	if p.AllowSameHost && strings.EqualFold(originUrl.Host, host) {
+		return true
+	}
+	for _, allowed := range p.Origins {
+		if matchOrigin(allowed, originUrl) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin checks if origin matches the pattern, a pattern host beginning with `*.` matches any subdomain
+func matchOrigin(pattern string, origin *url.URL) bool {
+	if pattern == "*" {
+		return true
+	}
+	patternUrl, err := url.Parse(pattern)
+	if err != nil || patternUrl.Host == "" {
+		return false
+	}
+	if !strings.EqualFold(patternUrl.Scheme, origin.Scheme) || patternUrl.Port() != origin.Port() {
+		return false
+	}
+	patternHost := strings.ToLower(patternUrl.Hostname())
+	originHost := strings.ToLower(origin.Hostname())
+	if strings.HasPrefix(patternHost, "*.") {
+		return strings.HasSuffix(originHost, patternHost[1:])
+	}
+	return patternHost == originHost
+}
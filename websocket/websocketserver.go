@@ -1,11 +1,11 @@
 package websocket
 
 import (
-	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/ngaut/log"
 	"net/http"
 	"strings"
+	"sync"
 )
 
 type Server struct {
@@ -15,6 +15,9 @@ type Server struct {
 	quitChannel            chan *Client
 	clients                map[*Client]bool            // TODO: remove?
 	writeMessageChannels   map[*chan Message][]*Client // TODO: threadsafe
+	histories              map[*chan Message]*History  // Replayable record of each write channel
+	historiesMutex         sync.RWMutex
+	originPolicy           *OriginPolicy
 	upgrader               websocket.Upgrader
 }
 
@@ -23,8 +26,18 @@ type RegisterChannel struct {
 	Action  bool
 }
 
+const (
+	FullMode  = "full"  // Clients receive every update in full
+	PatchMode = "patch" // Clients receive merge patches and new plays only
+)
+
 type Client struct {
-	Socket  *websocket.Conn // Websocket connection
+	Socket     *websocket.Conn // Websocket connection
+	Mode       string          // FullMode or PatchMode, defaults to FullMode
+	Encoding   Encoding        `json:"-"` // Negotiated by subprotocol or encoding query, defaults to json
+	writeMutex sync.Mutex      // Connections support only one concurrent writer
+	onClose    func()          // Invoked once when the websocket is closed
+	closeOnce  sync.Once
 }
 
 type Message struct {
@@ -32,9 +45,18 @@ type Message struct {
 	Type     string
 	Id       int
 	Contents map[string]interface{}
+	Patch    *Message `json:"-"` // Optional equivalent of this message for PatchMode clients
 }
 
-func CreateWebsocketServer() *Server {
+// ForMode returns the representation of the message that should be sent to clients subscribed with mode
+func (m Message) ForMode(mode string) Message {
+	if mode == PatchMode && m.Patch != nil {
+		return *m.Patch
+	}
+	return m
+}
+
+func CreateWebsocketServer(originPolicy *OriginPolicy) *Server {
 	server := Server{
 		ClientMessageChannel:   make(chan Message),
 		RegisterChannelChannel: make(chan RegisterChannel),
@@ -42,7 +64,13 @@ func CreateWebsocketServer() *Server {
 		quitChannel:            make(chan *Client),
 		clients:                make(map[*Client]bool),
 		writeMessageChannels:   make(map[*chan Message][]*Client),
-		upgrader:               websocket.Upgrader{},
+		histories:              make(map[*chan Message]*History),
+		originPolicy:           originPolicy,
+		upgrader: websocket.Upgrader{
+			CheckOrigin:       originPolicy.Allowed,
+			EnableCompression: true, // permessage-deflate, when requested by the client
+			Subprotocols:      subprotocols(),
+		},
 	}
 
 	go server.websocketCloserHandler()
@@ -64,21 +92,28 @@ func CreateWebsocketServer() *Server {
 	return &server
 }
 
-func (s *Server) BaseWebsocketHandler(w http.ResponseWriter, r *http.Request) *Client {
-	requestOrigin := r.Header.Get("Origin")
-	if !strings.Contains(requestOrigin, fmt.Sprintf("http://%s", r.Host)) {
-		log.Errorf("Invalid Origin %s", requestOrigin)
+// BaseWebsocketHandler upgrades the request into a websocket client, onClose (optional) is invoked once the client is closed
+func (s *Server) BaseWebsocketHandler(w http.ResponseWriter, r *http.Request, onClose func()) *Client {
+	if !s.originPolicy.Allowed(r) {
+		log.Errorf("Invalid Origin %s", r.Header.Get("Origin"))
 		http.Error(w, "Invalid Origin", 403)
 		return nil
 	}
-	conn := s.upgradeToWebsocket(w, r)
+	conn := s.upgradeToWebsocket(w, r, onClose)
 	go s.listenToClient(conn)
 	return conn
 }
 
 func (s *Server) WriteToClient(client *Client, message Message) bool {
 	///log.Debugf("Writing to a client at %s with message type: %s", client.Socket.RemoteAddr().String(), message.Type)
-	err := client.Socket.WriteJSON(message)
+	data, err := client.Encoding.Encode(message.ForMode(client.Mode))
+	if err != nil {
+		log.Errorf("Error encoding %s message: %s", client.Encoding.Name(), err)
+		return true
+	}
+	client.writeMutex.Lock()
+	err = client.Socket.WriteMessage(client.Encoding.FrameType(), data)
+	client.writeMutex.Unlock()
 	if err != nil {
 		if !s.checkClientClosed(client, err) {
 			log.Errorf("Error writing json: %s", err)
@@ -98,6 +133,13 @@ func (s *Server) RegisterClientToWriteChannel(client *Client, writeChannel *chan
 	return true
 }
 
+// GetChannelHistory returns the history of a registered write channel, or nil if the channel is not registered
+func (s *Server) GetChannelHistory(writeChannel *chan Message) *History {
+	s.historiesMutex.RLock()
+	defer s.historiesMutex.RUnlock()
+	return s.histories[writeChannel]
+}
+
 func (s *Server) RegisterClientMessageReceiver(endpoint string, channel *chan Message) bool {
 	if _, ok := s.ClientMessageReceivers[endpoint]; ok {
 		log.Errorf("Receiver with key %s has already been registered", endpoint)
@@ -113,11 +155,18 @@ func (s *Server) registerNewChannels() {
 		registerChannel := <-s.RegisterChannelChannel
 		channel := registerChannel.Channel
 		if registerChannel.Action {
+			history := newHistory()
+			s.historiesMutex.Lock()
+			s.histories[channel] = history
+			s.historiesMutex.Unlock()
 			s.writeMessageChannels[channel] = make([]*Client, 0)
-			go s.writeToClients(channel)
+			go s.writeToClients(channel, history)
 		} else {
 			close(*channel)
 			delete(s.writeMessageChannels, channel)
+			s.historiesMutex.Lock()
+			delete(s.histories, channel)
+			s.historiesMutex.Unlock()
 		}
 	}
 }
@@ -127,17 +176,27 @@ func (s *Server) registerNewChannels() {
 func (s *Server) websocketCloserHandler() {
 	for {
 		client := <-s.quitChannel
-		log.Debugf("Closing websocket %s", client.Socket.RemoteAddr())
-		_ = client.Socket.Close()
-		delete(s.clients, client)
+		client.closeOnce.Do(func() {
+			log.Debugf("Closing websocket %s", client.Socket.RemoteAddr())
+			_ = client.Socket.Close()
+			delete(s.clients, client)
+			if client.onClose != nil {
+				client.onClose()
+			}
+		})
 	}
 }
 
 // Goroutine function
 // writeToClients is to be invoked when a channel wishes to write to its clients
-func (s *Server) writeToClients(channel *chan Message) {
+// Every message is also recorded in the channel's history for non websocket transports
+func (s *Server) writeToClients(channel *chan Message, history *History) {
 	for {
-		msg := <-*channel
+		msg, ok := <-*channel
+		if !ok {
+			return
+		}
+		history.Append(msg)
 		i := 0
 		channels := s.writeMessageChannels[channel]
 		for _, client := range s.writeMessageChannels[channel] {
@@ -182,7 +241,7 @@ func (s *Server) listenToClient(client *Client) {
 }
 
 // upgradeToWebsocket upgrades a http call into a websocket
-func (s *Server) upgradeToWebsocket(w http.ResponseWriter, r *http.Request) *Client {
+func (s *Server) upgradeToWebsocket(w http.ResponseWriter, r *http.Request, onClose func()) *Client {
 	socket, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, "Could not open websocket connection", http.StatusBadRequest)
@@ -191,8 +250,17 @@ func (s *Server) upgradeToWebsocket(w http.ResponseWriter, r *http.Request) *Cli
 	}
 
 	log.Debugf("Registering a client at %s", socket.RemoteAddr().String())
+	encoding := GetEncoding(strings.TrimPrefix(socket.Subprotocol(), subprotocolPrefix))
+	if encoding == nil {
+		encoding = GetEncoding(r.URL.Query().Get("encoding"))
+	}
+	if encoding == nil {
+		encoding = DefaultEncoding()
+	}
 	client := Client{
-		Socket: socket,
+		Socket:   socket,
+		Encoding: encoding,
+		onClose:  onClose,
 	}
 	s.clients[&client] = true
 